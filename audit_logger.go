@@ -0,0 +1,491 @@
+package gobase
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-faster/errors"
+	"gopkg.in/Graylog2/go-gelf.v2/gelf"
+)
+
+// Fields set by AuditLogger and GELF message fields (they would replace hashed values), can't be passed as record fields
+var auditReservedFields = map[string]bool{
+	"actor": true, "action": true, "target": true, "audit_seq": true, "prev_hash": true, "hash": true,
+	"version": true, "host": true, "short_message": true, "full_message": true, "timestamp": true, "level": true, "facility": true,
+}
+
+// AuditLogger writes admin actions (bans, config changes, ...) as append-only records with mandatory
+// actor, action and target fields, separately from debug logging. Records are GELF messages of kind "audit"
+// at LevelNotice, chained by hash: every record has "audit_seq" (1, 2, ...), "prev_hash" (hash of previous record,
+// empty for the first one) and "hash", which is HMAC-SHA256 of the record's canonical JSON without "hash" field.
+// Changing, removing or reordering records breaks the chain, see VerifyAuditLog. The key keeps whoever can write
+// the log file from rewriting the chain; removed last records are detected with a Checkpoint kept elsewhere.
+type AuditLogger struct {
+	mu                 sync.Mutex
+	writer             MessageWriter
+	facility, hostname string
+	key                []byte
+	seq                uint64
+	prevHash           string
+}
+
+// AuditCheckpoint is seq and hash of an audit record, "seq:hash" as text
+type AuditCheckpoint struct {
+	Seq  uint64
+	Hash string
+}
+
+func (c AuditCheckpoint) String() string {
+	return fmt.Sprintf("%d:%s", c.Seq, c.Hash)
+}
+
+func ParseAuditCheckpoint(s string) (AuditCheckpoint, error) {
+	seq, hash, ok := strings.Cut(s, ":")
+	n, err := strconv.ParseUint(seq, 10, 64)
+	if !ok || err != nil || n == 0 || hash == "" {
+		return AuditCheckpoint{}, errors.Errorf("Invalid audit checkpoint '%s' (expected seq:hash)", s)
+	}
+
+	return AuditCheckpoint{Seq: n, Hash: hash}, nil
+}
+
+// NewAuditLogger makes audit logger with HMAC key of record hashes
+func NewAuditLogger(writer MessageWriter, facility, selfHostname string, key []byte) (*AuditLogger, error) {
+	if len(key) == 0 {
+		return nil, errors.New("Audit logger requires HMAC key")
+	}
+
+	return &AuditLogger{
+		writer:   writer,
+		facility: facility,
+		hostname: selfHostname,
+		key:      key,
+	}, nil
+}
+
+// OpenAuditLog opens audit log file (see FileWriter, format is always "json"),
+// continuing hash chain of the last record in it or in the newest rotated backup.
+// Incomplete last line (write interrupted by crash) is cut off, the last record must have hash made with key.
+func OpenAuditLog(config FileWriterConfig, facility, selfHostname string, key []byte) (*AuditLogger, error) {
+	if len(key) == 0 {
+		return nil, errors.New("Audit logger requires HMAC key")
+	}
+
+	config.Format = "json"
+	syncLevel := LevelDebug // every record is fsynced
+	config.SyncLevel = &syncLevel
+
+	if err := truncateTornAuditLine(config.Path); err != nil {
+		return nil, errors.Wrap(err, "repair audit log")
+	}
+
+	last, err := lastAuditRecord(config.Path, key)
+	if err != nil {
+		return nil, errors.Wrap(err, "read last audit record")
+	}
+
+	w, err := NewFileWriter(config)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := NewAuditLogger(w, facility, selfHostname, key)
+	if err != nil {
+		w.Close()
+		return nil, err
+	}
+	a.Resume(last)
+
+	return a, nil
+}
+
+// Resume continues hash chain after the last record written before restart
+func (a *AuditLogger) Resume(last AuditCheckpoint) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.seq, a.prevHash = last.Seq, last.Hash
+}
+
+// Checkpoint returns seq and hash of the last record written. Kept outside of the log (database, remote log),
+// it is the end of the chain VerifyAuditLog checks the log against: the chain alone does not show
+// that the last records were removed.
+func (a *AuditLogger) Checkpoint() AuditCheckpoint {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return AuditCheckpoint{Seq: a.seq, Hash: a.prevHash}
+}
+
+// Record writes audit record, keyvals are alternating keys and values like in Logger helpers.
+// Fields are redacted with DefaultRedactor. Returns error if actor, action or target is empty,
+// a reserved field is passed, or the sink fails (the record is not part of the chain then).
+func (a *AuditLogger) Record(actor, action, target string, keyvals ...any) error {
+	if actor == "" || action == "" || target == "" {
+		return errors.Errorf("Audit record requires actor, action and target (got '%s', '%s', '%s')", actor, action, target)
+	}
+
+	fields := DefaultRedactor.RedactFields(KeyvalsToFields(keyvals...))
+	for k := range fields {
+		if auditReservedFields[k] {
+			return errors.Errorf("Audit record field '%s' is reserved", k)
+		}
+	}
+
+	fields["actor"], fields["action"], fields["target"] = actor, action, target
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	fields["audit_seq"] = a.seq + 1
+	fields["prev_hash"] = a.prevHash
+
+	m := &gelf.Message{
+		Version:  "1.1",
+		Host:     a.hostname,
+		Short:    "audit",
+		Full:     fmt.Sprintf("%s %s %s", actor, action, target),
+		TimeUnix: float64(time.Now().UnixNano()) / float64(time.Second),
+		Level:    int32(LevelNotice),
+		Extra:    fields,
+		Facility: a.facility,
+	}
+
+	// Fields are normalized to what is read back from JSON, so that the hash can be verified
+	record, err := auditCanonicalRecord(m)
+	if err != nil {
+		return err
+	}
+
+	hash := auditHash(a.key, record)
+
+	m.Extra = map[string]any{}
+	for k, v := range record {
+		switch k {
+		case "version", "host", "short_message", "full_message", "timestamp", "level", "facility":
+		default:
+			m.Extra[k] = v
+		}
+	}
+	m.Extra["hash"] = hash
+
+	if err := a.writer.WriteMessage(m); err != nil {
+		return errors.Wrap(err, "write audit record")
+	}
+
+	a.seq++
+	a.prevHash = hash
+
+	return nil
+}
+
+func (a *AuditLogger) Close() error {
+	return a.writer.Close()
+}
+
+// auditCanonicalRecord returns message as decoded from its GELF JSON, numbers are kept as json.Number
+func auditCanonicalRecord(m *gelf.Message) (map[string]any, error) {
+	var buf bytes.Buffer
+	if err := m.MarshalJSONBuf(&buf); err != nil {
+		return nil, errors.Wrap(err, "marshal audit record")
+	}
+
+	return decodeAuditRecord(buf.Bytes())
+}
+
+func decodeAuditRecord(data []byte) (map[string]any, error) {
+	var record map[string]any
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&record); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// auditHash is hex HMAC-SHA256 of record JSON without "hash" field (encoding/json sorts map keys)
+func auditHash(key []byte, record map[string]any) string {
+	withoutHash := make(map[string]any, len(record))
+	for k, v := range record {
+		if k != "hash" {
+			withoutHash[k] = v
+		}
+	}
+
+	data, _ := json.Marshal(withoutHash)
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// AuditVerifyResult describes verified audit log
+type AuditVerifyResult struct {
+	Records  int
+	FirstSeq uint64
+	LastSeq  uint64
+	LastHash string
+}
+
+// AuditVerifier checks hash chain of audit log read in parts (rotated files, oldest first), see VerifyAuditLog
+type AuditVerifier struct {
+	key        []byte
+	start, end AuditCheckpoint
+	endFound   bool
+	result     AuditVerifyResult
+}
+
+// NewAuditVerifier verifies log starting with record 1, or with the record after start checkpoint
+// (older files were deleted by rotation), and having the record of end checkpoint; zero checkpoints are not used
+func NewAuditVerifier(key []byte, start, end AuditCheckpoint) *AuditVerifier {
+	return &AuditVerifier{key: key, start: start, end: end}
+}
+
+// VerifyAuditLog reads audit records (JSON lines, as written by OpenAuditLog) and checks hash chain:
+// each record hash (made with key), prev_hash equal to the previous record hash, audit_seq increasing by 1,
+// mandatory fields. Records before the first one are checked with start, after the last one with end
+// (see AuditLogger.Checkpoint and NewAuditVerifier).
+func VerifyAuditLog(r io.Reader, key []byte, start, end AuditCheckpoint) (AuditVerifyResult, error) {
+	v := NewAuditVerifier(key, start, end)
+	if err := v.Verify(r); err != nil {
+		return v.result, err
+	}
+	return v.Finish()
+}
+
+// Finish checks that the log has the record of end checkpoint, returns verified records
+func (v *AuditVerifier) Finish() (AuditVerifyResult, error) {
+	if v.end.Seq != 0 && !v.endFound {
+		return v.result, errors.Errorf("log ends with record %d, records up to checkpoint %d were removed", v.result.LastSeq, v.end.Seq)
+	}
+	return v.result, nil
+}
+
+// Verify verifies next part of the audit log, chained to records verified before
+func (v *AuditVerifier) Verify(r io.Reader) error {
+	result := &v.result
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		record, err := decodeAuditRecord(scanner.Bytes())
+		if err != nil {
+			return errors.Wrapf(err, "line %d", line)
+		}
+
+		for _, k := range []string{"actor", "action", "target"} {
+			if s, _ := record[k].(string); s == "" {
+				return errors.Errorf("line %d: record has no %s", line, k)
+			}
+		}
+
+		seq, err := auditRecordSeq(record)
+		if err != nil {
+			return errors.Wrapf(err, "line %d", line)
+		}
+
+		hash, _ := record["hash"].(string)
+		if !hmac.Equal([]byte(hash), []byte(auditHash(v.key, record))) {
+			return errors.Errorf("line %d: record %d was modified or written with other key, hash does not match", line, seq)
+		}
+
+		prevHash, _ := record["prev_hash"].(string)
+
+		switch {
+		case result.Records == 0 && v.start.Hash == "" && (seq != 1 || prevHash != ""):
+			return errors.Errorf("line %d: log starts with record %d, records before it were removed (verify with checkpoint of record %d)", line, seq, seq-1)
+		case result.Records == 0 && v.start.Hash != "" && (seq != v.start.Seq+1 || prevHash != v.start.Hash):
+			return errors.Errorf("line %d: record %d does not continue checkpoint %s", line, seq, v.start)
+		case result.Records > 0 && seq != result.LastSeq+1:
+			return errors.Errorf("line %d: record %d follows record %d, records are missing or reordered", line, seq, result.LastSeq)
+		case result.Records > 0 && prevHash != result.LastHash:
+			return errors.Errorf("line %d: record %d prev_hash does not match hash of record %d", line, seq, result.LastSeq)
+		}
+
+		if seq == v.end.Seq {
+			if hash != v.end.Hash {
+				return errors.Errorf("line %d: record %d does not match checkpoint %s", line, seq, v.end)
+			}
+			v.endFound = true
+		}
+
+		if result.Records == 0 {
+			result.FirstSeq = seq
+		}
+		result.Records++
+		result.LastSeq = seq
+		result.LastHash = hash
+	}
+
+	return scanner.Err()
+}
+
+func auditRecordSeq(record map[string]any) (uint64, error) {
+	n, ok := record["audit_seq"].(json.Number)
+	if !ok {
+		return 0, errors.New("record has no audit_seq")
+	}
+
+	var seq uint64
+	if _, err := fmt.Sscan(n.String(), &seq); err != nil || seq == 0 {
+		return 0, errors.Errorf("invalid audit_seq '%s'", n)
+	}
+
+	return seq, nil
+}
+
+// AuditLogFiles returns rotated backups of audit log at path (see FileWriter) and the file itself, oldest first
+func AuditLogFiles(path string) ([]string, error) {
+	backups, err := fileBackups(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Backup names end with timestamp, sorting by name sorts by age
+	sort.Strings(backups)
+
+	if fileExists(path) {
+		backups = append(backups, path)
+	}
+
+	return backups, nil
+}
+
+// OpenAuditLogFile opens audit log file, decompressing rotated .gz backups
+func OpenAuditLogFile(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasSuffix(path, ".gz") {
+		return f, nil
+	}
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return struct {
+		io.Reader
+		io.Closer
+	}{gz, f}, nil
+}
+
+// lastAuditRecord returns the last record in the audit log file or the newest backup, zero if there are none
+func lastAuditRecord(path string, key []byte) (AuditCheckpoint, error) {
+	files, err := AuditLogFiles(path)
+	if err != nil {
+		return AuditCheckpoint{}, err
+	}
+
+	for i := len(files) - 1; i >= 0; i-- {
+		r, err := OpenAuditLogFile(files[i])
+		if err != nil {
+			return AuditCheckpoint{}, err
+		}
+
+		var last []byte
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			if len(bytes.TrimSpace(scanner.Bytes())) > 0 {
+				last = append(last[:0], scanner.Bytes()...)
+			}
+		}
+		err = scanner.Err()
+		r.Close()
+
+		if err != nil {
+			return AuditCheckpoint{}, errors.Wrap(err, files[i])
+		}
+		if last == nil {
+			continue
+		}
+
+		record, err := decodeAuditRecord(last)
+		if err != nil {
+			return AuditCheckpoint{}, errors.Wrap(err, files[i])
+		}
+
+		seq, err := auditRecordSeq(record)
+		if err != nil {
+			return AuditCheckpoint{}, errors.Wrap(err, files[i])
+		}
+
+		hash, _ := record["hash"].(string)
+		if !hmac.Equal([]byte(hash), []byte(auditHash(key, record))) {
+			return AuditCheckpoint{}, errors.Errorf("%s: record %d was modified or written with other key, hash does not match", files[i], seq)
+		}
+
+		return AuditCheckpoint{Seq: seq, Hash: hash}, nil
+	}
+
+	return AuditCheckpoint{}, nil
+}
+
+// truncateTornAuditLine cuts off incomplete last line of audit log file, left by a write interrupted by crash
+func truncateTornAuditLine(path string) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	// Find the end of the last complete line, reading the file backwards
+	buf := make([]byte, 64*1024)
+	end := stat.Size()
+	for end > 0 {
+		n := int64(len(buf))
+		if end < n {
+			n = end
+		}
+		if _, err := f.ReadAt(buf[:n], end-n); err != nil {
+			return err
+		}
+
+		if i := bytes.LastIndexByte(buf[:n], '\n'); i >= 0 {
+			end = end - n + int64(i) + 1
+			break
+		}
+		end -= n
+	}
+
+	if end == stat.Size() {
+		return nil
+	}
+
+	stderrLog.Printf("WARN audit log %s ends with incomplete record (%d bytes), it is cut off", path, stat.Size()-end)
+
+	return f.Truncate(end)
+}
@@ -0,0 +1,126 @@
+package gobase
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testAuditKey = []byte("test-key")
+
+// writeTestAuditLog writes n records, returns log lines and checkpoint of the last record
+func writeTestAuditLog(t *testing.T, path string, n int) ([]string, AuditCheckpoint) {
+	t.Helper()
+
+	a, err := OpenAuditLog(FileWriterConfig{Path: path}, "bot", "host1", testAuditKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		if err := a.Record("admin", "ban", "user42", "reason", "spam"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return strings.SplitAfter(strings.TrimSuffix(readTestFile(t, path), "\n"), "\n"), a.Checkpoint()
+}
+
+func TestVerifyAuditLog(t *testing.T) {
+	lines, end := writeTestAuditLog(t, filepath.Join(t.TempDir(), "audit.log"), 3)
+
+	result, err := VerifyAuditLog(strings.NewReader(strings.Join(lines, "")), testAuditKey, AuditCheckpoint{}, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Records != 3 || result.FirstSeq != 1 || result.LastSeq != 3 || result.LastHash != end.Hash {
+		t.Errorf("unexpected result %+v, checkpoint %v", result, end)
+	}
+
+	parsed, err := ParseAuditCheckpoint(end.String())
+	if err != nil || parsed != end {
+		t.Errorf("checkpoint %v parsed as %v (%v)", end, parsed, err)
+	}
+}
+
+func TestVerifyAuditLogRemovedRecords(t *testing.T) {
+	lines, end := writeTestAuditLog(t, filepath.Join(t.TempDir(), "audit.log"), 3)
+
+	if _, err := VerifyAuditLog(strings.NewReader(strings.Join(lines[1:], "")), testAuditKey, AuditCheckpoint{}, end); err == nil {
+		t.Error("log without the first record verified")
+	}
+	if _, err := VerifyAuditLog(strings.NewReader(strings.Join(lines[:2], "")), testAuditKey, AuditCheckpoint{}, end); err == nil {
+		t.Error("log without the last record verified with end checkpoint")
+	}
+
+	// Rotated away records are skipped with checkpoint of the record before
+	first, err := VerifyAuditLog(strings.NewReader(lines[0]), testAuditKey, AuditCheckpoint{}, AuditCheckpoint{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := AuditCheckpoint{Seq: first.LastSeq, Hash: first.LastHash}
+	if _, err := VerifyAuditLog(strings.NewReader(strings.Join(lines[1:], "")), testAuditKey, start, end); err != nil {
+		t.Errorf("log after start checkpoint not verified: %v", err)
+	}
+}
+
+func TestVerifyAuditLogWrongKey(t *testing.T) {
+	lines, end := writeTestAuditLog(t, filepath.Join(t.TempDir(), "audit.log"), 2)
+
+	if _, err := VerifyAuditLog(strings.NewReader(strings.Join(lines, "")), []byte("other-key"), AuditCheckpoint{}, end); err == nil {
+		t.Error("log verified with other key")
+	}
+}
+
+func TestOpenAuditLogTruncatesTornRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	lines, _ := writeTestAuditLog(t, path, 2)
+
+	// Write of the second record interrupted by crash
+	torn := lines[0] + lines[1][:len(lines[1])/2]
+	if err := os.WriteFile(path, []byte(torn), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	lines, end := writeTestAuditLog(t, path, 1)
+	if end.Seq != 2 || len(lines) != 2 {
+		t.Fatalf("audit log not resumed after the last complete record: %v\n%s", end, strings.Join(lines, ""))
+	}
+	if _, err := VerifyAuditLog(strings.NewReader(strings.Join(lines, "")), testAuditKey, AuditCheckpoint{}, end); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := OpenAuditLog(FileWriterConfig{Path: path}, "bot", "host1", []byte("other-key")); err == nil {
+		t.Error("audit log opened with other key")
+	}
+}
+
+func TestAuditRecordRejectsGELFFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	a, err := OpenAuditLog(FileWriterConfig{Path: path}, "bot", "host1", testAuditKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, k := range []string{"level", "host", "timestamp", "facility"} {
+		if err := a.Record("admin", "config", "chat", k, "debug"); err == nil {
+			t.Errorf("record with field %s accepted", k)
+		}
+	}
+	if err := a.Record("admin", "config", "chat", "new_level", "debug"); err != nil {
+		t.Fatal(err)
+	}
+	a.Close()
+
+	if _, err := VerifyAuditLog(strings.NewReader(readTestFile(t, path)), testAuditKey, AuditCheckpoint{}, a.Checkpoint()); err != nil {
+		t.Error(err)
+	}
+	a, err = OpenAuditLog(FileWriterConfig{Path: path}, "bot", "host1", testAuditKey)
+	if err != nil {
+		t.Fatalf("audit log not reopened: %v", err)
+	}
+	a.Close()
+}
@@ -0,0 +1,98 @@
+// Command auditverify checks hash chain of audit log written by gobase.OpenAuditLog,
+// including rotated backups, and exits with status 1 if records were modified, removed or reordered.
+// HMAC key is read from -key-file or AUDIT_LOG_KEY environment variable. Without -start the log must begin
+// with record 1; -end is the checkpoint (AuditLogger.Checkpoint) stored outside of the log:
+//
+//	auditverify -key-file /etc/bot/audit.key -end 1042:9c1e... /var/log/bot/audit.log
+//	auditverify -start 512:3f5a... audit.log.2024-01-02T15-04-05.000.gz audit.log
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/flogram-lab/gobase"
+)
+
+func main() {
+	keyFile := flag.String("key-file", "", "file with HMAC key of record hashes (default AUDIT_LOG_KEY environment variable)")
+	start := flag.String("start", "", "checkpoint seq:hash of the record before the first one (older records were rotated away)")
+	end := flag.String("end", "", "checkpoint seq:hash of the last record written, the log must have it")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-key-file file] [-start seq:hash] [-end seq:hash] audit.log | file...\n\n", os.Args[0])
+		fmt.Fprintln(flag.CommandLine.Output(), "A single path is verified together with its rotated backups, several paths are verified in given order.")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	key := []byte(os.Getenv("AUDIT_LOG_KEY"))
+	if *keyFile != "" {
+		data, err := os.ReadFile(*keyFile)
+		if err != nil {
+			fail(err)
+		}
+		key = []byte(strings.TrimSpace(string(data)))
+	}
+	if len(key) == 0 {
+		fail(fmt.Errorf("no HMAC key, set -key-file or AUDIT_LOG_KEY"))
+	}
+
+	startCheckpoint := checkpoint(*start)
+	endCheckpoint := checkpoint(*end)
+
+	files := flag.Args()
+	switch len(files) {
+	case 0:
+		flag.Usage()
+		os.Exit(2)
+	case 1:
+		var err error
+		if files, err = gobase.AuditLogFiles(files[0]); err != nil {
+			fail(err)
+		}
+		if len(files) == 0 {
+			fail(fmt.Errorf("no audit log at %s", flag.Arg(0)))
+		}
+	}
+
+	verifier := gobase.NewAuditVerifier(key, startCheckpoint, endCheckpoint)
+
+	for _, path := range files {
+		r, err := gobase.OpenAuditLogFile(path)
+		if err != nil {
+			fail(err)
+		}
+
+		err = verifier.Verify(r)
+		r.Close()
+		if err != nil {
+			fail(fmt.Errorf("%s: %w", path, err))
+		}
+	}
+
+	result, err := verifier.Finish()
+	if err != nil {
+		fail(err)
+	}
+
+	fmt.Printf("OK: %d records (%d..%d) in %d files, checkpoint %d:%s\n", result.Records, result.FirstSeq, result.LastSeq, len(files), result.LastSeq, result.LastHash)
+}
+
+func checkpoint(s string) gobase.AuditCheckpoint {
+	if s == "" {
+		return gobase.AuditCheckpoint{}
+	}
+
+	c, err := gobase.ParseAuditCheckpoint(s)
+	if err != nil {
+		fail(err)
+	}
+	return c
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "FAILED:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,136 @@
+// Command gelftail receives GELF messages (TCP and UDP, like Graylog input) and prints them,
+// so services using NewGelfLogger can be debugged without Graylog:
+//
+//	gelftail -listen :12201 -level info -kind grpc,queue -request Xy12ab
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/flogram-lab/gobase"
+	"gopkg.in/Graylog2/go-gelf.v2/gelf"
+)
+
+func main() {
+	listen := flag.String("listen", ":12201", "address to listen on, TCP and UDP")
+	level := flag.String("level", "debug", "print messages of this and more severe levels")
+	facilities := flag.String("facility", "", "comma separated facilities to print, all if empty")
+	kinds := flag.String("kind", "", "comma separated message kinds to print, all if empty")
+	request := flag.String("request", "", "print only messages of this request_uid (and requests nested in it)")
+	color := flag.String("color", "auto", "colourise output: auto, always or never")
+	raw := flag.Bool("json", false, "print messages as GELF JSON lines")
+	flag.Parse()
+
+	maxLevel, err := gobase.ParseLevel(*level)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	filter := messageFilter{
+		maxLevel:   maxLevel,
+		facilities: splitList(*facilities),
+		kinds:      splitList(*kinds),
+		request:    *request,
+	}
+
+	var writer gobase.MessageWriter
+	if *raw {
+		writer = gobase.NewJSONWriter(os.Stdout)
+	} else {
+		writer = gobase.NewConsoleWriter(os.Stdout, useColor(*color))
+	}
+
+	var mu sync.Mutex
+
+	receiver, err := gobase.NewGelfReceiver(*listen, func(m *gelf.Message) {
+		if !filter.match(m) {
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if !*raw {
+			fmt.Fprintf(os.Stdout, "%s@%s ", m.Facility, m.Host)
+		}
+		if err := writer.WriteMessage(m); err != nil {
+			log.Println("print message:", err)
+		}
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("Listening for GELF messages on %s (tcp, udp)", receiver.Addr())
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	<-signals
+
+	receiver.Close()
+}
+
+type messageFilter struct {
+	maxLevel   gobase.Level
+	facilities []string
+	kinds      []string
+	request    string
+}
+
+func (f messageFilter) match(m *gelf.Message) bool {
+	if gobase.Level(m.Level) > f.maxLevel {
+		return false
+	}
+
+	if len(f.facilities) > 0 && !contains(f.facilities, m.Facility) {
+		return false
+	}
+
+	if len(f.kinds) > 0 && !contains(f.kinds, m.Short) {
+		return false
+	}
+
+	if f.request != "" {
+		// Nested request IDs are "parent/child", request matches whole segments on any level
+		ruid, _ := m.Extra["request_uid"].(string)
+		if !strings.Contains("/"+ruid+"/", "/"+f.request+"/") {
+			return false
+		}
+	}
+
+	return true
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func useColor(mode string) bool {
+	switch mode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+
+	return gobase.IsTerminal(os.Stdout)
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+
+	"gopkg.in/Graylog2/go-gelf.v2/gelf"
+)
+
+func TestMessageFilterRequest(t *testing.T) {
+	f := messageFilter{maxLevel: 7, request: "abc"}
+
+	for ruid, want := range map[string]bool{
+		"abc":         true,
+		"abc/def":     true,
+		"x/abc":       true,
+		"x/abc/def":   true,
+		"abcd":        false,
+		"x/abcd":      false,
+		"x/abcd/abc1": false,
+		"":            false,
+	} {
+		m := &gelf.Message{Level: 6, Extra: map[string]any{"request_uid": ruid}}
+		if got := f.match(m); got != want {
+			t.Errorf("request %q matched %q: %v, want %v", f.request, ruid, got, want)
+		}
+	}
+}
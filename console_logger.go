@@ -0,0 +1,157 @@
+package gobase
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-faster/errors"
+	"gopkg.in/Graylog2/go-gelf.v2/gelf"
+)
+
+const (
+	colorReset   = "\033[0m"
+	colorRed     = "\033[31m"
+	colorRedBold = "\033[1;31m"
+	colorYellow  = "\033[33m"
+	colorGreen   = "\033[32m"
+	colorCyan    = "\033[36m"
+	colorGray    = "\033[90m"
+)
+
+var levelColors = [...]string{colorRedBold, colorRedBold, colorRedBold, colorRed, colorYellow, colorGreen, colorCyan, colorGray}
+
+// ConsoleWriter prints GELF messages as human-readable lines, optionally colourised by level:
+//
+//	15:04:05.000 ERROR  [request_uid] kind: message key=value ... (dir/file.go:line)
+type ConsoleWriter struct {
+	mu         sync.Mutex
+	out        io.Writer
+	color      bool
+	timeFormat string
+}
+
+const (
+	consoleTimeFormat  = "15:04:05.000"
+	fileTextTimeFormat = "2006-01-02T15:04:05.000Z07:00" // RFC3339 with milliseconds
+)
+
+func NewConsoleWriter(out io.Writer, color bool) *ConsoleWriter {
+	return &ConsoleWriter{out: out, color: color, timeFormat: consoleTimeFormat}
+}
+
+func (w *ConsoleWriter) WriteMessage(m *gelf.Message) error {
+	_, err := w.writeMessage(m)
+	return err
+}
+
+func (w *ConsoleWriter) writeMessage(m *gelf.Message) (int, error) {
+	var sb strings.Builder
+
+	ts := time.Unix(0, int64(m.TimeUnix*float64(time.Second)))
+	sb.WriteString(ts.Format(w.timeFormat))
+	sb.WriteByte(' ')
+
+	level := fmt.Sprintf("%-6s", Level(m.Level).String())
+	if w.color && m.Level >= 0 && int(m.Level) < len(levelColors) {
+		level = levelColors[m.Level] + level + colorReset
+	}
+	sb.WriteString(level)
+	sb.WriteByte(' ')
+
+	if ruid, ok := m.Extra["request_uid"].(string); ok && ruid != "" {
+		sb.WriteString("[" + ruid + "] ")
+	}
+
+	sb.WriteString(m.Short)
+	if m.Full != "" {
+		sb.WriteString(": ")
+		sb.WriteString(m.Full)
+	}
+
+	keys := make([]string, 0, len(m.Extra))
+	for k := range m.Extra {
+		switch k {
+		case "request_uid", "file", "line", "function":
+		default:
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		sb.WriteByte(' ')
+		if w.color {
+			sb.WriteString(colorGray + k + "=" + colorReset)
+		} else {
+			sb.WriteString(k + "=")
+		}
+		sb.WriteString(formatConsoleValue(m.Extra[k]))
+	}
+
+	if file, ok := m.Extra["file"]; ok {
+		caller := fmt.Sprintf(" (%v:%v)", file, m.Extra["line"])
+		if w.color {
+			caller = colorGray + caller + colorReset
+		}
+		sb.WriteString(caller)
+	}
+
+	sb.WriteByte('\n')
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return io.WriteString(w.out, sb.String())
+}
+
+func (w *ConsoleWriter) Close() error {
+	return nil
+}
+
+func formatConsoleValue(v any) string {
+	s := fmt.Sprint(v)
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// NewConsoleLogger makes logger printing human-readable lines to out (local development)
+func NewConsoleLogger(facility, selfHostname string, out io.Writer, color bool) Logger {
+	return newGelfLogger(NewConsoleWriter(out, color), facility, selfHostname)
+}
+
+// NewStdoutLogger makes logger writing to stdout in given format, which is usually read from config:
+//
+//	"json": JSON-lines with GELF field names (see JSONWriter)
+//	"console": human-readable, colourised if stdout is a terminal and NO_COLOR is not set
+func NewStdoutLogger(format, facility, selfHostname string) (Logger, error) {
+	switch format {
+	case "json":
+		return NewJSONLogger(facility, selfHostname, os.Stdout), nil
+	case "console":
+		return NewConsoleLogger(facility, selfHostname, os.Stdout, IsTerminal(os.Stdout)), nil
+	default:
+		return nil, errors.Errorf("Invalid logger format '%s' (expected 'json' or 'console')", format)
+	}
+}
+
+// IsTerminal tells if f is a terminal and colours are not disabled with NO_COLOR environment variable
+func IsTerminal(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+
+	stat, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return stat.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,61 @@
+package gobase
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConsoleWriter(t *testing.T) {
+	var out bytes.Buffer
+	logger := NewConsoleLogger("bot", "host1", &out, false).AddRequestID("req1")
+	logger.Warn("db", "slow query", "took_ms", 1500, "query", "select 1")
+
+	line := out.String()
+	if strings.Contains(line, "\033[") {
+		t.Errorf("colours without terminal: %q", line)
+	}
+	if !strings.Contains(line, ` WARN   [req1] db: slow query query="select 1" took_ms=1500 (module/console_logger_test.go:`) ||
+		!strings.HasSuffix(line, "\n") || strings.Count(line, "\n") != 1 {
+		t.Errorf("unexpected line %q", line)
+	}
+
+	out.Reset()
+	NewConsoleLogger("bot", "host1", &out, true).Error("db", "failed")
+	if !strings.Contains(out.String(), colorRed+"ERROR "+colorReset) {
+		t.Errorf("level not colourised: %q", out.String())
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if IsTerminal(f) {
+		t.Error("file is terminal")
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	if IsTerminal(w) {
+		t.Error("pipe is terminal")
+	}
+
+	if tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0); err == nil {
+		defer tty.Close()
+		t.Setenv("NO_COLOR", "1")
+		if IsTerminal(tty) {
+			t.Error("terminal with NO_COLOR is colourised")
+		}
+	}
+}
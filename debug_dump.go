@@ -0,0 +1,165 @@
+package gobase
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DebugDump is state of a hanging process: stacks of all goroutines, heap summary, JobQueue and Goroutines group states
+type DebugDump struct {
+	Time         time.Time
+	NumGoroutine int
+	Stacks       string
+	Memory       runtime.MemStats
+	Queues       []JobQueueState
+	Groups       map[string]int // live goroutines of Goroutines group by name
+}
+
+func NewDebugDump() *DebugDump {
+	d := &DebugDump{
+		Time:         time.Now(),
+		NumGoroutine: runtime.NumGoroutine(),
+		Stacks:       goroutinesDump(),
+		Queues:       JobQueueStates(),
+		Groups:       Goroutines.Counts(),
+	}
+
+	runtime.ReadMemStats(&d.Memory)
+
+	sort.Slice(d.Queues, func(i, j int) bool { return d.Queues[i].Name < d.Queues[j].Name })
+
+	return d
+}
+
+// Summary formats everything except goroutine stacks
+func (d *DebugDump) Summary() string {
+	var sb strings.Builder
+
+	m := &d.Memory
+	fmt.Fprintf(&sb, "goroutines: %d\n", d.NumGoroutine)
+	fmt.Fprintf(&sb, "heap: alloc %s, in use %s, objects %d, sys %s\n",
+		formatBytes(m.HeapAlloc), formatBytes(m.HeapInuse), m.HeapObjects, formatBytes(m.Sys))
+	fmt.Fprintf(&sb, "gc: %d cycles, pause total %s", m.NumGC, time.Duration(m.PauseTotalNs))
+	if m.NumGC > 0 {
+		fmt.Fprintf(&sb, ", last %s ago", time.Since(time.Unix(0, int64(m.LastGC))).Round(time.Millisecond))
+	}
+	sb.WriteByte('\n')
+
+	sb.WriteString("queues:\n")
+	for _, q := range d.Queues {
+		fmt.Fprintf(&sb, "  %s: pending %d/%d, done %d", q.Name, q.Pending, q.Backlog, q.Done)
+		if q.Running {
+			fmt.Fprintf(&sb, ", job running for %s", q.RunningFor.Round(time.Millisecond))
+		}
+		sb.WriteByte('\n')
+	}
+
+	names := make([]string, 0, len(d.Groups))
+	for name := range d.Groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	sb.WriteString("goroutines by name:\n")
+	for _, name := range names {
+		fmt.Fprintf(&sb, "  %s: %d\n", name, d.Groups[name])
+	}
+
+	return sb.String()
+}
+
+func (d *DebugDump) String() string {
+	return fmt.Sprintf("debug dump at %s\n\n%s\n%s", d.Time.Format(time.RFC3339Nano), d.Summary(), d.Stacks)
+}
+
+// Log sends dump to logger with kind "debug_dump": summary message with heap fields,
+// then goroutine stacks split into messages fitting GELF limits, with "dump_id", "part" and "parts" fields.
+// Messages are LevelCritical, not checked by level and sampler (dump is requested explicitly),
+// ErrorReporter skips them (so that a dump is not a burst of error events).
+func (d *DebugDump) Log(l Logger) {
+	dumpID := NewRequestID()
+
+	parts := splitDumpText(d.Stacks, panicDumpFieldLimit)
+
+	logUnfiltered(l, LevelCritical, "debug_dump", d.Summary(), map[string]any{
+		"dump_id":      dumpID,
+		"goroutines":   d.NumGoroutine,
+		"heap_alloc":   d.Memory.HeapAlloc,
+		"heap_inuse":   d.Memory.HeapInuse,
+		"heap_objects": d.Memory.HeapObjects,
+		"sys":          d.Memory.Sys,
+		"num_gc":       d.Memory.NumGC,
+		"parts":        len(parts),
+	})
+
+	for i, part := range parts {
+		logUnfiltered(l, LevelCritical, "debug_dump", part, map[string]any{
+			"dump_id": dumpID,
+			"part":    i + 1,
+			"parts":   len(parts),
+		})
+	}
+}
+
+// WriteFile writes dump to a new file in dir, returns its path
+func (d *DebugDump) WriteFile(dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("dump-%s-%d.txt", d.Time.Format("2006-01-02T15-04-05.000"), os.Getpid()))
+
+	return path, os.WriteFile(path, []byte(d.String()), 0644)
+}
+
+// splitDumpText splits text into parts of at most limit bytes, at line ends when possible
+func splitDumpText(text string, limit int) []string {
+	var parts []string
+
+	for len(text) > limit {
+		cut := strings.LastIndexByte(text[:limit], '\n') + 1
+		if cut <= 0 {
+			cut = limit
+		}
+		parts = append(parts, text[:cut])
+		text = text[cut:]
+	}
+
+	if text != "" {
+		parts = append(parts, text)
+	}
+
+	return parts
+}
+
+func formatBytes(n uint64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GiB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+// dumpOnSignal makes dump, logs it and writes it to dir if set
+func dumpOnSignal(l Logger, dir string) {
+	d := NewDebugDump()
+
+	if dir != "" {
+		if path, err := d.WriteFile(dir); err != nil {
+			stderrLog.Println("ERROR write debug dump:", err)
+		} else {
+			logUnfiltered(l, LevelCritical, "debug_dump", "debug dump written to "+path, map[string]any{"dump_file": path})
+		}
+	}
+
+	d.Log(l)
+}
@@ -0,0 +1,39 @@
+//go:build unix
+
+package gobase
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// WatchDumpSignal logs DebugDump (see DebugDump.Log) on signals until ctx is done, without terminating the process.
+// The dump is also written to a file in dir, if it is not empty. SIGQUIT is used if no signals are given.
+// If l is nil, the default logger is used.
+func WatchDumpSignal(ctx context.Context, l Logger, dir string, signals ...os.Signal) {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGQUIT}
+	}
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, signals...)
+
+	go func() {
+		defer signal.Stop(c)
+
+		for {
+			select {
+			case <-c:
+				logger := l
+				if logger == nil {
+					logger = DefaultLogger()
+				}
+				dumpOnSignal(logger, dir)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
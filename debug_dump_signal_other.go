@@ -0,0 +1,12 @@
+//go:build !unix
+
+package gobase
+
+import (
+	"context"
+	"os"
+)
+
+// WatchDumpSignal does nothing on platforms without SIGQUIT, use NewDebugDump directly
+func WatchDumpSignal(ctx context.Context, l Logger, dir string, signals ...os.Signal) {
+}
@@ -0,0 +1,94 @@
+package gobase
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func hasQueueState(name string) bool {
+	for _, state := range JobQueueStates() {
+		if state.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func TestJobQueueStatesOnlyRunning(t *testing.T) {
+	q := NewJobQueue("dump_test", NewRecordingLogger(), 1)
+	if hasQueueState("dump_test") {
+		t.Fatal("queue not running is in JobQueueStates")
+	}
+
+	q.Initialize(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		q.Run()
+	}()
+
+	q.Join(context.Background(), func(context.Context) {
+		if !hasQueueState("dump_test") {
+			t.Error("running queue is not in JobQueueStates")
+		}
+		if !strings.Contains(NewDebugDump().Summary(), "dump_test: pending 0/1, done 0, job running") {
+			t.Error("running job is not in dump summary")
+		}
+	})
+
+	q.Stop()
+	<-done
+
+	if hasQueueState("dump_test") {
+		t.Error("stopped queue is in JobQueueStates")
+	}
+}
+
+func TestDebugDumpLog(t *testing.T) {
+	logger := NewRecordingLogger()
+
+	dump := NewDebugDump()
+	dump.Log(logger)
+
+	messages := logger.Find(LevelCritical, "debug_dump", "goroutines:", HasField("dump_id"), HasField("heap_alloc"))
+	if len(messages) != 1 {
+		t.Fatalf("no dump summary logged:\n%s", logger.dump())
+	}
+
+	parts := logger.Find(LevelCritical, "debug_dump", "goroutine ", HasField("part"))
+	if len(parts) == 0 || parts[0].Fields["dump_id"] != messages[0].Fields["dump_id"] {
+		t.Errorf("no dump parts logged:\n%s", logger.dump())
+	}
+}
+
+func TestDebugDumpNotReportedNorFiltered(t *testing.T) {
+	receiver, err := NewErrorReportReceiver("127.0.0.1:0", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Close()
+
+	reporter, err := NewErrorReporter(ErrorReporterConfig{DSN: receiver.DSN(), RetryInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer Levels.ResetAll()
+	Levels.SetGlobal(LevelEmergency)
+
+	logger := NewRecordingLogger()
+	logger.SetErrorReporter(reporter)
+	sampler := NewSampler(time.Hour, LevelError, SamplingPolicy{})
+	sampler.Policies[LevelCritical] = SamplingPolicy{}
+	logger.SetSampler(sampler)
+
+	NewDebugDump().Log(logger)
+	reporter.Close()
+
+	if n := len(receiver.Events()); n != 0 {
+		t.Errorf("debug dump sent %d error events", n)
+	}
+	logger.AssertLogged(t, LevelCritical, "debug_dump", "goroutines:")
+}
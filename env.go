@@ -42,6 +42,7 @@ func GetenvInt(key string, defaultValue int, allowNull bool) int {
 }
 
 // Using os.Getenv, get map of key=value strings from environment
+// Logging the map is safe: loggers redact values of denied keys (tokens, passwords, ...), see Redactor.
 func GetenvMap(keys ...string) map[string]any {
 	v := make(map[string]any, len(keys))
 	for i := range keys {
@@ -0,0 +1,236 @@
+package gobase
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-faster/errors"
+)
+
+// ErrorWithFields is an error carrying structured fields, which ErrorField adds to log message
+type ErrorWithFields interface {
+	error
+	ErrorFields() map[string]any
+}
+
+type fieldsError struct {
+	err    error
+	fields map[string]any
+}
+
+func (e *fieldsError) Error() string {
+	return e.err.Error()
+}
+
+func (e *fieldsError) Unwrap() error {
+	return e.err
+}
+
+func (e *fieldsError) ErrorFields() map[string]any {
+	return e.fields
+}
+
+// Format keeps "%+v" output of wrapped error (with go-faster/errors frames)
+func (e *fieldsError) Format(s fmt.State, verb rune) {
+	if verb == 'v' && s.Flag('+') {
+		fmt.Fprintf(s, "%+v", e.err)
+		return
+	}
+	fmt.Fprint(s, e.err.Error())
+}
+
+// WithFields attaches fields (alternating keys and values, like Logger helpers) to err.
+// Error message is not changed, errors.Is and errors.As see through it.
+func WithFields(err error, keyvals ...any) error {
+	if err == nil {
+		return nil
+	}
+
+	return &fieldsError{err: err, fields: KeyvalsToFields(keyvals...)}
+}
+
+// ErrorField converts error to GELF fields:
+//
+//	error        err.Error()
+//	error_type   type name of the innermost error (of the first one, if errors were joined)
+//	error_chain  each wrapped layer adding a message, as "message [type]", outermost first, one per line;
+//	             layers of joined errors (errors.Join, several %w) follow in order
+//	error_origin file:line where innermost go-faster/errors error or wrap was created
+//	error_stack  "%+v" output with frames of every layer, if any were recorded
+//
+// Fields attached with WithFields are added too, outer layers take precedence.
+// An error in place of a key in Logger helpers keyvals is expanded with ErrorField:
+//
+//	logger.Error("db", "query failed", err, "user_id", 1)
+func ErrorField(err error) map[string]any {
+	if err == nil {
+		return map[string]any{}
+	}
+
+	fields := map[string]any{
+		"error": err.Error(),
+	}
+
+	var chain []string
+	var rootType, origin string
+
+	// Depth-first, type and origin are of the first innermost error
+	var walk func(err error)
+	walk = func(err error) {
+		for layer := err; layer != nil; layer = errors.Unwrap(layer) {
+			message, location := errorLayer(layer)
+			if location != "" && rootType == "" {
+				origin = location
+			}
+
+			typeName := errorTypeName(layer)
+			if message != "" {
+				chain = append(chain, fmt.Sprintf("%s [%s]", message, typeName))
+			}
+
+			if withFields, ok := layer.(ErrorWithFields); ok {
+				for k, v := range withFields.ErrorFields() {
+					if _, ok := fields[k]; !ok {
+						fields[k] = v
+					}
+				}
+			}
+
+			if joined, ok := layer.(interface{ Unwrap() []error }); ok {
+				for _, e := range joined.Unwrap() {
+					walk(e)
+				}
+				return
+			}
+
+			if errors.Unwrap(layer) == nil && rootType == "" {
+				rootType = typeName
+			}
+		}
+	}
+	walk(err)
+
+	if rootType == "" {
+		// Only joined nil errors, from a custom error type
+		rootType = errorTypeName(err)
+	}
+
+	fields["error_type"] = rootType
+	fields["error_chain"] = strings.Join(chain, "\n")
+
+	if origin != "" {
+		fields["error_origin"] = origin
+	}
+
+	if stack := fmt.Sprintf("%+v", err); stack != err.Error() {
+		fields["error_stack"] = stack
+	}
+
+	return fields
+}
+
+// errorTypeName returns type of error, "*errors.errorString" of go-faster/errors is reported as "errors.New"
+func errorTypeName(err error) string {
+	name := fmt.Sprintf("%T", err)
+
+	switch name {
+	case "*errors.wrapError":
+		return "errors.Wrap"
+	case "*errors.errorString":
+		return "errors.New"
+	case "*gobase.fieldsError":
+		return "gobase.WithFields"
+	}
+
+	return name
+}
+
+// errorLayer returns message added by this layer only, without messages of wrapped errors,
+// and "dir/file.go:line" where it was created, if recorded by go-faster/errors
+func errorLayer(err error) (message, location string) {
+	if f, ok := err.(errors.Formatter); ok {
+		p := &layerPrinter{}
+		f.FormatError(p)
+		if p.message.Len() > 0 {
+			return p.message.String(), p.location
+		}
+	}
+
+	message = err.Error()
+
+	// fmt.Errorf("context: %w", err) style
+	if next := errors.Unwrap(err); next != nil {
+		message = strings.TrimSuffix(message, next.Error())
+		message = strings.TrimSuffix(message, ": ")
+	}
+
+	// errors.Join adds no message of its own
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		texts := make([]string, 0, len(joined.Unwrap()))
+		for _, e := range joined.Unwrap() {
+			if e != nil {
+				texts = append(texts, e.Error())
+			}
+		}
+		if message == strings.Join(texts, "\n") {
+			message = ""
+		}
+	}
+
+	return message, ""
+}
+
+// errorValuesText replaces errors in field value (nested maps and slices are copied) with their text,
+// JSON encodes most errors as {}
+func errorValuesText(v any) any {
+	switch v := v.(type) {
+	case error:
+		return v.Error()
+	case map[string]any:
+		values := make(map[string]any, len(v))
+		for k := range v {
+			values[k] = errorValuesText(v[k])
+		}
+		return values
+	case []any:
+		values := make([]any, len(v))
+		for i := range v {
+			values[i] = errorValuesText(v[i])
+		}
+		return values
+	default:
+		return v
+	}
+}
+
+// layerPrinter collects message and frame location printed by errors.Formatter
+type layerPrinter struct {
+	message  strings.Builder
+	location string
+	detail   bool
+}
+
+func (p *layerPrinter) Print(args ...any) {
+	if !p.detail {
+		p.message.WriteString(fmt.Sprint(args...))
+	}
+}
+
+func (p *layerPrinter) Printf(format string, args ...any) {
+	if !p.detail {
+		p.message.WriteString(fmt.Sprintf(format, args...))
+		return
+	}
+
+	// errors.Frame prints "function\n    " and then "file:line\n"
+	if format == "%s:%d\n" && len(args) == 2 {
+		file, _ := args[0].(string)
+		p.location = fmt.Sprintf("%s/%s:%v", filepath.Base(filepath.Dir(file)), filepath.Base(file), args[1])
+	}
+}
+
+func (p *layerPrinter) Detail() bool {
+	p.detail = true
+	return true
+}
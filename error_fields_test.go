@@ -0,0 +1,44 @@
+package gobase
+
+import (
+	stderrors "errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-faster/errors"
+)
+
+func TestErrorFieldJoined(t *testing.T) {
+	errA := WithFields(errors.New("a failed"), "a_id", 1)
+	errB := fmt.Errorf("b failed: %w", WithFields(stderrors.New("timeout"), "b_id", 2))
+
+	fields := ErrorField(errors.Wrap(stderrors.Join(errA, errB), "close"))
+
+	chain := fields["error_chain"].(string)
+	for _, layer := range []string{"close [errors.Wrap]", "a failed [errors.New]", "b failed [*fmt.wrapError]", "timeout [errors.New]"} {
+		if !strings.Contains(chain, layer) {
+			t.Errorf("error_chain has no %q:\n%s", layer, chain)
+		}
+	}
+	if strings.Contains(chain, "joinError") {
+		t.Errorf("error_chain has join layer:\n%s", chain)
+	}
+
+	if fields["error_type"] != "errors.New" || fields["a_id"] != 1 || fields["b_id"] != 2 {
+		t.Errorf("unexpected fields %v", fields)
+	}
+}
+
+func TestErrorFieldValueWithoutRedactor(t *testing.T) {
+	logger := NewRecordingLogger()
+	logger.redactor = nil
+
+	logger.Info("test", "message", "cause", errors.New("disk full"), "nested", map[string]any{"cause": errors.New("timeout")})
+
+	logger.AssertLogged(t, LevelInfo, "test", "message", FieldEquals("cause", "disk full"))
+
+	if nested := logger.Messages()[0].Fields["nested"].(map[string]any); nested["cause"] != "timeout" {
+		t.Errorf("nested error not converted: %#v", nested["cause"])
+	}
+}
@@ -0,0 +1,210 @@
+package gobase
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-faster/errors"
+)
+
+// ErrorReportReceiver is a Sentry-compatible endpoint for tests and local development.
+// It accepts events of store and envelope requests of project 1, see DSN.
+type ErrorReportReceiver struct {
+	handler func(ev *ErrorEvent)
+	key     string
+
+	listener net.Listener
+	server   *http.Server
+	status   atomic.Int32
+
+	mu     sync.Mutex
+	events []*ErrorEvent
+}
+
+// NewErrorReportReceiver listens on addr ("127.0.0.1:0" picks free port).
+// handler is called for every received event; if it is nil, events are kept and returned by Events.
+func NewErrorReportReceiver(addr string, handler func(ev *ErrorEvent)) (*ErrorReportReceiver, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, errors.Wrap(err, "listen tcp")
+	}
+
+	r := &ErrorReportReceiver{
+		handler:  handler,
+		key:      RandStringBytesMaskImprSrcSB(32),
+		listener: listener,
+	}
+	r.status.Store(http.StatusOK)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/1/store/", r.handleStore)
+	mux.HandleFunc("POST /api/1/envelope/", r.handleEnvelope)
+	r.server = &http.Server{Handler: mux}
+
+	go r.server.Serve(listener)
+
+	return r, nil
+}
+
+// DSN returns DSN of the receiver, for ErrorReporterConfig
+func (r *ErrorReportReceiver) DSN() string {
+	return "http://" + r.key + "@" + r.listener.Addr().String() + "/1"
+}
+
+// SetStatus makes receiver reject requests with HTTP status code (e.g. 503 to test offline queue), 200 accepts them
+func (r *ErrorReportReceiver) SetStatus(code int) {
+	r.status.Store(int32(code))
+}
+
+// Events returns received events, when receiver was created without handler
+func (r *ErrorReportReceiver) Events() []*ErrorEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]*ErrorEvent(nil), r.events...)
+}
+
+func (r *ErrorReportReceiver) Close() error {
+	return r.server.Close()
+}
+
+func (r *ErrorReportReceiver) receive(ev *ErrorEvent) {
+	if r.handler != nil {
+		r.handler(ev)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, ev)
+}
+
+// accept checks status and auth, returns request body
+func (r *ErrorReportReceiver) accept(w http.ResponseWriter, req *http.Request) ([]byte, bool) {
+	if status := int(r.status.Load()); status != http.StatusOK {
+		http.Error(w, http.StatusText(status), status)
+		return nil, false
+	}
+
+	if !strings.Contains(req.Header.Get("X-Sentry-Auth"), "sentry_key="+r.key) {
+		http.Error(w, "invalid sentry_key", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	var body io.Reader = req.Body
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(req.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return nil, false
+		}
+		defer gz.Close()
+		body = gz
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	return data, true
+}
+
+func (r *ErrorReportReceiver) handleStore(w http.ResponseWriter, req *http.Request) {
+	data, ok := r.accept(w, req)
+	if !ok {
+		return
+	}
+
+	ev := &ErrorEvent{}
+	if err := json.Unmarshal(data, ev); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	r.receive(ev)
+
+	json.NewEncoder(w).Encode(map[string]string{"id": ev.EventID})
+}
+
+func (r *ErrorReportReceiver) handleEnvelope(w http.ResponseWriter, req *http.Request) {
+	data, ok := r.accept(w, req)
+	if !ok {
+		return
+	}
+
+	events, err := DecodeSentryEnvelope(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id := ""
+	for _, ev := range events {
+		id = ev.EventID
+		r.receive(ev)
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"id": id})
+}
+
+// DecodeSentryEnvelope returns events of envelope, other items (sessions, attachments, ...) are skipped
+func DecodeSentryEnvelope(data []byte) ([]*ErrorEvent, error) {
+	rd := bufio.NewReader(bytes.NewReader(data))
+
+	// Envelope header
+	if _, err := rd.ReadBytes('\n'); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	var events []*ErrorEvent
+
+	for {
+		line, err := rd.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) == 0 {
+			if err != nil {
+				return events, nil
+			}
+			continue
+		}
+
+		var item struct {
+			Type   string `json:"type"`
+			Length int    `json:"length"`
+		}
+		if err := json.Unmarshal(line, &item); err != nil {
+			return nil, errors.Wrap(err, "envelope item header")
+		}
+
+		var payload []byte
+		if item.Length > 0 {
+			payload = make([]byte, item.Length)
+			if _, err := io.ReadFull(rd, payload); err != nil {
+				return nil, errors.Wrap(err, "envelope item payload")
+			}
+			rd.ReadByte() // newline after payload
+		} else if payload, err = rd.ReadBytes('\n'); err != nil && err != io.EOF {
+			return nil, errors.Wrap(err, "envelope item payload")
+		}
+
+		if item.Type != "event" {
+			continue
+		}
+
+		ev := &ErrorEvent{}
+		if err := json.Unmarshal(payload, ev); err != nil {
+			return nil, errors.Wrap(err, "envelope event")
+		}
+		events = append(events, ev)
+	}
+}
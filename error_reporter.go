@@ -0,0 +1,755 @@
+package gobase
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	mathrand "math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-faster/errors"
+	"gopkg.in/Graylog2/go-gelf.v2/gelf"
+)
+
+type ErrorReporterConfig struct {
+	// DSN of Sentry-compatible project, e.g. "https://public_key@sentry.example.com/42"
+	DSN string
+	// API is "envelope" (default) or "store" (legacy endpoint, older self-hosted servers)
+	API         string
+	Release     string
+	Environment string
+	// Level is the most verbose level of logged messages reported, LevelError if nil; panics are always reported
+	Level *Level
+	// SampleRate is fraction (0..1) of logged messages reported, 1 if nil (0 reports none); panics are not sampled
+	SampleRate *float64
+	// QueueDir keeps events that failed to send (endpoint down) until it is available again, also after restart.
+	// If empty, they are kept in memory.
+	QueueDir string
+	// QueueSize is max number of events waiting to be sent, and kept offline, oldest are dropped; default 100
+	QueueSize int
+	// RetryInterval between attempts to send offline events, default 1m
+	RetryInterval time.Duration
+	// Timeout of a request, default 10s
+	Timeout time.Duration
+}
+
+// SentryDSN is parsed DSN: "{scheme}://{public_key}[:{secret_key}]@{host}[/{path}]/{project_id}"
+type SentryDSN struct {
+	Scheme    string
+	PublicKey string
+	SecretKey string
+	Host      string // with port
+	Path      string // prefix of API path, without trailing slash
+	ProjectID string
+}
+
+func ParseSentryDSN(dsn string) (*SentryDSN, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid DSN")
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.Errorf("Invalid DSN scheme '%s'", u.Scheme)
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return nil, errors.New("Invalid DSN (no public key)")
+	}
+	if u.Host == "" {
+		return nil, errors.New("Invalid DSN (no host)")
+	}
+
+	path := strings.TrimSuffix(u.Path, "/")
+	i := strings.LastIndexByte(path, '/')
+	if i < 0 || path[i+1:] == "" {
+		return nil, errors.New("Invalid DSN (no project ID)")
+	}
+
+	d := &SentryDSN{
+		Scheme:    u.Scheme,
+		PublicKey: u.User.Username(),
+		Host:      u.Host,
+		Path:      path[:i],
+		ProjectID: path[i+1:],
+	}
+	d.SecretKey, _ = u.User.Password()
+
+	return d, nil
+}
+
+func (d *SentryDSN) String() string {
+	user := d.PublicKey
+	if d.SecretKey != "" {
+		user += ":" + d.SecretKey
+	}
+	return fmt.Sprintf("%s://%s@%s%s/%s", d.Scheme, user, d.Host, d.Path, d.ProjectID)
+}
+
+func (d *SentryDSN) StoreURL() string {
+	return fmt.Sprintf("%s://%s%s/api/%s/store/", d.Scheme, d.Host, d.Path, d.ProjectID)
+}
+
+func (d *SentryDSN) EnvelopeURL() string {
+	return fmt.Sprintf("%s://%s%s/api/%s/envelope/", d.Scheme, d.Host, d.Path, d.ProjectID)
+}
+
+// AuthHeader is value of "X-Sentry-Auth" request header
+func (d *SentryDSN) AuthHeader() string {
+	auth := "Sentry sentry_version=7, sentry_client=gobase/1.0, sentry_key=" + d.PublicKey
+	if d.SecretKey != "" {
+		auth += ", sentry_secret=" + d.SecretKey
+	}
+	return auth
+}
+
+// ErrorEvent is an event in Sentry format
+type ErrorEvent struct {
+	EventID     string                `json:"event_id"`
+	Timestamp   time.Time             `json:"timestamp"`
+	Level       string                `json:"level"`
+	Logger      string                `json:"logger,omitempty"` // message kind
+	Platform    string                `json:"platform"`
+	Message     string                `json:"message,omitempty"`
+	ServerName  string                `json:"server_name,omitempty"`
+	Release     string                `json:"release,omitempty"`
+	Environment string                `json:"environment,omitempty"`
+	Tags        map[string]string     `json:"tags,omitempty"`
+	Fingerprint []string              `json:"fingerprint,omitempty"`
+	Exception   *ErrorEventExceptions `json:"exception,omitempty"`
+	Extra       map[string]any        `json:"extra,omitempty"`
+}
+
+type ErrorEventExceptions struct {
+	Values []ErrorEventException `json:"values"`
+}
+
+type ErrorEventException struct {
+	Type       string                `json:"type"`
+	Value      string                `json:"value"`
+	Mechanism  *ErrorEventMechanism  `json:"mechanism,omitempty"`
+	Stacktrace *ErrorEventStacktrace `json:"stacktrace,omitempty"`
+}
+
+type ErrorEventMechanism struct {
+	Type    string `json:"type"` // "panic" or "log"
+	Handled bool   `json:"handled"`
+}
+
+type ErrorEventStacktrace struct {
+	Frames []ErrorEventFrame `json:"frames"` // outermost call first, as Sentry expects
+}
+
+type ErrorEventFrame struct {
+	Function string `json:"function"`
+	Module   string `json:"module,omitempty"`
+	Filename string `json:"filename"`
+	AbsPath  string `json:"abs_path"`
+	Lineno   int    `json:"lineno"`
+	InApp    bool   `json:"in_app"`
+}
+
+// SentryLevel maps syslog level to Sentry event level
+func SentryLevel(level Level) string {
+	switch {
+	case level <= LevelCritical:
+		return "fatal"
+	case level == LevelError:
+		return "error"
+	case level == LevelWarning:
+		return "warning"
+	case level == LevelDebug:
+		return "debug"
+	default:
+		return "info"
+	}
+}
+
+// ErrorReporter sends panics and logged errors as events to a Sentry-compatible endpoint, in background.
+// Events are grouped by fingerprint made of message kind and function names of the stack (line numbers are
+// left out so that events survive unrelated edits), and tagged with facility, hostname, request ID and release.
+//
+//	logger.SetErrorReporter(reporter)
+//
+// Events that failed to send are kept in the offline queue and retried.
+type ErrorReporter struct {
+	config     ErrorReporterConfig
+	level      Level
+	sampleRate float64
+	dsn        *SentryDSN
+	httpc      *http.Client
+	offline    *errorEventQueue
+	queue      chan *ErrorEvent
+	flush      chan chan struct{}
+	done       chan struct{}
+	closed     sync.WaitGroup
+
+	mu      sync.RWMutex // guards stopped against enqueue, so no event is queued after Close drains the queue
+	stopped bool
+}
+
+func NewErrorReporter(config ErrorReporterConfig) (*ErrorReporter, error) {
+	dsn, err := ParseSentryDSN(config.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	switch config.API {
+	case "":
+		config.API = "envelope"
+	case "envelope", "store":
+	default:
+		return nil, errors.Errorf("Invalid error reporter API '%s'", config.API)
+	}
+
+	level := LevelError
+	if config.Level != nil {
+		level = *config.Level
+	}
+	sampleRate := 1.0
+	if config.SampleRate != nil {
+		sampleRate = *config.SampleRate
+	}
+	if sampleRate < 0 || sampleRate > 1 {
+		return nil, errors.Errorf("Invalid error reporter sample rate %v", sampleRate)
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = 100
+	}
+	if config.RetryInterval <= 0 {
+		config.RetryInterval = time.Minute
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+
+	offline, err := newErrorEventQueue(config.QueueDir, config.QueueSize)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &ErrorReporter{
+		config:     config,
+		level:      level,
+		sampleRate: sampleRate,
+		dsn:        dsn,
+		httpc:      &http.Client{Timeout: config.Timeout},
+		offline:    offline,
+		queue:      make(chan *ErrorEvent, config.QueueSize),
+		flush:      make(chan chan struct{}),
+		done:       make(chan struct{}),
+	}
+
+	r.closed.Add(1)
+	go r.run()
+
+	return r, nil
+}
+
+// loggerReporter is ErrorReporter of a logger with the logger values its events are made with
+type loggerReporter struct {
+	reporter *ErrorReporter
+	facility string
+	hostname string
+	redactor *Redactor
+}
+
+// errorReporterOf returns ErrorReporter of the logger (see GelfLogger.SetErrorReporter), nil reporter if it has none
+func errorReporterOf(l Logger) loggerReporter {
+	if rl, ok := l.(interface{ errorReporter() loggerReporter }); ok {
+		return rl.errorReporter()
+	}
+	return loggerReporter{}
+}
+
+// ReportPanic sends panic report with message and panic value redacted by redactor (nil disables redaction),
+// returns event ID
+func (r *ErrorReporter) ReportPanic(report *PanicReport, facility, hostname string, redactor *Redactor) string {
+	message, value := report.Message(), fmt.Sprint(report.Value)
+	if redactor != nil {
+		message, value = redactor.RedactString(message), redactor.RedactString(value)
+	}
+
+	ev := r.newEvent(LevelCritical, report.Kind, message, facility, hostname, report.RequestID)
+
+	exception := ErrorEventException{
+		Type:       fmt.Sprintf("%T", report.Value),
+		Value:      value,
+		Mechanism:  &ErrorEventMechanism{Type: "panic"},
+		Stacktrace: errorEventStacktrace(report.Frames),
+	}
+	if report.Error != nil {
+		exception.Type = errorTypeName(report.Error)
+	}
+	ev.Exception = &ErrorEventExceptions{Values: []ErrorEventException{exception}}
+	ev.Fingerprint = []string{stackFingerprint(report.Kind, report.Frames)}
+
+	if report.Title != "" {
+		ev.Tags["panic_title"] = report.Title
+	}
+	ev.Extra = map[string]any{
+		"goroutines": report.Goroutines,
+		"go_version": report.GoVersion,
+		"uptime_s":   int64(report.Uptime.Seconds()),
+	}
+	if report.VCSRevision != "" {
+		ev.Extra["vcs_revision"] = report.VCSRevision
+	}
+	if report.CrashFile != "" {
+		ev.Extra["crash_file"] = report.CrashFile
+	}
+
+	r.enqueue(ev)
+
+	return ev.EventID
+}
+
+// reportMessage sends logged message if its level is reported and it passes sampling, returns event ID or "".
+// skip is number of frames between reportMessage caller and the code that logged.
+func (r *ErrorReporter) reportMessage(m *gelf.Message, skip int) string {
+	// Debug dump is requested explicitly, it is not an error
+	if Level(m.Level) > r.level || m.Short == "debug_dump" {
+		return ""
+	}
+	if r.sampleRate < 1 && mathrand.Float64() >= r.sampleRate {
+		return ""
+	}
+
+	requestID, _ := m.Extra["request_uid"].(string)
+	ev := r.newEvent(Level(m.Level), m.Short, m.Full, m.Facility, m.Host, requestID)
+
+	frames := callerFrames(skip + 1)
+
+	exception := ErrorEventException{
+		Type:       m.Short,
+		Value:      m.Full,
+		Mechanism:  &ErrorEventMechanism{Type: "log", Handled: true},
+		Stacktrace: errorEventStacktrace(frames),
+	}
+	if errType, ok := m.Extra["error_type"].(string); ok {
+		exception.Type = errType
+		if errText, ok := m.Extra["error"].(string); ok {
+			exception.Value = errText
+		}
+	}
+	ev.Exception = &ErrorEventExceptions{Values: []ErrorEventException{exception}}
+	ev.Fingerprint = []string{stackFingerprint(m.Short, frames)}
+
+	ev.Extra = make(map[string]any, len(m.Extra))
+	for k, v := range m.Extra {
+		switch k {
+		case "request_uid", "file", "line", "function":
+		default:
+			ev.Extra[k] = v
+		}
+	}
+
+	r.enqueue(ev)
+
+	return ev.EventID
+}
+
+func (r *ErrorReporter) newEvent(level Level, kind, message, facility, hostname, requestID string) *ErrorEvent {
+	ev := &ErrorEvent{
+		EventID:     newEventID(),
+		Timestamp:   time.Now().UTC(),
+		Level:       SentryLevel(level),
+		Logger:      kind,
+		Platform:    "go",
+		Message:     message,
+		ServerName:  hostname,
+		Release:     r.config.Release,
+		Environment: r.config.Environment,
+		Tags:        map[string]string{"kind": kind},
+	}
+
+	if facility != "" {
+		ev.Tags["facility"] = facility
+	}
+	if hostname != "" {
+		ev.Tags["hostname"] = hostname
+	}
+	if requestID != "" {
+		ev.Tags["request_id"] = requestID
+	}
+	if r.config.Release != "" {
+		ev.Tags["release"] = r.config.Release
+	}
+
+	return ev
+}
+
+func (r *ErrorReporter) enqueue(ev *ErrorEvent) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.stopped {
+		// Closed reporter keeps events offline, to be sent after restart if QueueDir is set
+		r.offline.push(ev)
+		return
+	}
+
+	select {
+	case r.queue <- ev:
+	default:
+		stderrLog.Println("ERROR error reporter queue is full, event dropped:", ev.EventID)
+	}
+}
+
+// Flush sends queued events and tries to send offline ones
+func (r *ErrorReporter) Flush() {
+	c := make(chan struct{})
+	select {
+	case r.flush <- c:
+		<-c
+	case <-r.done:
+	}
+}
+
+// Close sends queued events, events that fail to send are kept in QueueDir
+func (r *ErrorReporter) Close() error {
+	r.mu.Lock()
+	if r.stopped {
+		r.mu.Unlock()
+		return nil
+	}
+	r.stopped = true
+	close(r.done)
+	r.mu.Unlock()
+
+	r.closed.Wait()
+	return nil
+}
+
+func (r *ErrorReporter) run() {
+	defer r.closed.Done()
+
+	ticker := time.NewTicker(r.config.RetryInterval)
+	defer ticker.Stop()
+
+	drain := func() {
+		for {
+			select {
+			case ev := <-r.queue:
+				r.deliver(ev)
+			default:
+				r.retryOffline()
+				return
+			}
+		}
+	}
+
+	r.retryOffline()
+
+	for {
+		select {
+		case ev := <-r.queue:
+			r.deliver(ev)
+		case <-ticker.C:
+			r.retryOffline()
+		case c := <-r.flush:
+			drain()
+			close(c)
+		case <-r.done:
+			drain()
+			return
+		}
+	}
+}
+
+// deliver sends event, or keeps it offline if endpoint is not available
+func (r *ErrorReporter) deliver(ev *ErrorEvent) {
+	if r.offline.len() > 0 {
+		// Endpoint was down recently, keep order of events
+		r.offline.push(ev)
+		return
+	}
+
+	if retry, err := r.send(ev); err != nil {
+		// Logging it through a logger could loop back here
+		stderrLog.Printf("ERROR send error event %s: %s", ev.EventID, err)
+		if retry {
+			r.offline.push(ev)
+		}
+	}
+}
+
+func (r *ErrorReporter) retryOffline() {
+	for {
+		ev := r.offline.peek()
+		if ev == nil {
+			return
+		}
+
+		retry, err := r.send(ev)
+		if err != nil && retry {
+			return
+		}
+		if err != nil {
+			stderrLog.Printf("ERROR send offline error event %s: %s", ev.EventID, err)
+		}
+
+		r.offline.pop()
+	}
+}
+
+// send makes request, retry tells if the failed event can be accepted later (network errors, rate limits, server errors)
+func (r *ErrorReporter) send(ev *ErrorEvent) (retry bool, err error) {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return false, err
+	}
+
+	endpoint, contentType, body := r.dsn.StoreURL(), "application/json", payload
+	if r.config.API == "envelope" {
+		endpoint, contentType, body = r.dsn.EnvelopeURL(), "application/x-sentry-envelope", sentryEnvelope(r.dsn, ev, payload)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-Sentry-Auth", r.dsn.AuthHeader())
+
+	resp, err := r.httpc.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode/100 == 2:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode/100 == 5:
+		return true, errors.Errorf("HTTP status %s", resp.Status)
+	default:
+		return false, errors.Errorf("HTTP status %s", resp.Status)
+	}
+}
+
+func sentryEnvelope(dsn *SentryDSN, ev *ErrorEvent, payload []byte) []byte {
+	header, _ := json.Marshal(map[string]any{
+		"event_id": ev.EventID,
+		"sent_at":  time.Now().UTC().Format(time.RFC3339Nano),
+		"dsn":      dsn.String(),
+	})
+	item, _ := json.Marshal(map[string]any{
+		"type":   "event",
+		"length": len(payload),
+	})
+
+	var buf bytes.Buffer
+	buf.Write(header)
+	buf.WriteByte('\n')
+	buf.Write(item)
+	buf.WriteByte('\n')
+	buf.Write(payload)
+	buf.WriteByte('\n')
+
+	return buf.Bytes()
+}
+
+func newEventID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// stackFingerprint hashes kind and function names of frames, without line numbers
+func stackFingerprint(kind string, frames []PanicFrame) string {
+	h := sha256.New()
+	h.Write([]byte(kind))
+	for _, f := range frames {
+		h.Write([]byte{'\n'})
+		h.Write([]byte(f.Function))
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// callerFrames returns stack of the caller, skip is number of frames between callerFrames caller and the first frame returned
+func callerFrames(skip int) []PanicFrame {
+	pc := make([]uintptr, 64)
+	pc = pc[:runtime.Callers(skip+2, pc)]
+
+	var frames []PanicFrame
+
+	iter := runtime.CallersFrames(pc)
+	for {
+		frame, more := iter.Next()
+		if frame.Function != "" {
+			frames = append(frames, PanicFrame{Function: frame.Function, File: frame.File, Line: frame.Line})
+		}
+		if !more {
+			break
+		}
+	}
+
+	return frames
+}
+
+// errorEventStacktrace converts frames (innermost first) to Sentry stacktrace (outermost first)
+func errorEventStacktrace(frames []PanicFrame) *ErrorEventStacktrace {
+	if len(frames) == 0 {
+		return nil
+	}
+
+	st := &ErrorEventStacktrace{Frames: make([]ErrorEventFrame, len(frames))}
+	for i, f := range frames {
+		module, function := splitFunctionName(f.Function)
+		st.Frames[len(frames)-1-i] = ErrorEventFrame{
+			Function: function,
+			Module:   module,
+			Filename: filepath.Base(filepath.Dir(f.File)) + "/" + filepath.Base(f.File),
+			AbsPath:  f.File,
+			Lineno:   f.Line,
+			InApp:    !strings.HasPrefix(f.Function, "runtime.") && !strings.HasPrefix(module, "github.com/flogram-lab/gobase"),
+		}
+	}
+
+	return st
+}
+
+// splitFunctionName splits "github.com/a/b.(*T).Method" into package path and function name
+func splitFunctionName(name string) (module, function string) {
+	slash := strings.LastIndexByte(name, '/')
+	dot := strings.IndexByte(name[slash+1:], '.')
+	if dot < 0 {
+		return "", name
+	}
+	return name[:slash+1+dot], name[slash+1+dot+1:]
+}
+
+// errorEventQueue keeps events that failed to send, as files in dir (named by time, to keep order) or in memory
+type errorEventQueue struct {
+	dir  string
+	size int
+
+	mu     sync.Mutex
+	events []*ErrorEvent // in memory, or loaded head of files in dir
+	files  []string
+}
+
+func newErrorEventQueue(dir string, size int) (*errorEventQueue, error) {
+	q := &errorEventQueue{dir: dir, size: size}
+
+	if dir == "" {
+		return q, nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrap(err, "error reporter queue dir")
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	q.files = files
+
+	return q, nil
+}
+
+func (q *errorEventQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.dir != "" {
+		return len(q.files)
+	}
+	return len(q.events)
+}
+
+func (q *errorEventQueue) push(ev *ErrorEvent) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.dir == "" {
+		q.events = append(q.events, ev)
+		if len(q.events) > q.size {
+			q.events = q.events[len(q.events)-q.size:]
+		}
+		return
+	}
+
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+
+	path := filepath.Join(q.dir, fmt.Sprintf("%d-%s.json", time.Now().UnixNano(), ev.EventID))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		stderrLog.Println("ERROR keep error event offline:", err)
+		return
+	}
+	q.files = append(q.files, path)
+
+	for len(q.files) > q.size {
+		os.Remove(q.files[0])
+		q.files = q.files[1:]
+	}
+}
+
+// peek returns the oldest event, or nil
+func (q *errorEventQueue) peek() *ErrorEvent {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.dir == "" {
+		if len(q.events) == 0 {
+			return nil
+		}
+		return q.events[0]
+	}
+
+	for len(q.files) > 0 {
+		data, err := os.ReadFile(q.files[0])
+		if err == nil {
+			ev := &ErrorEvent{}
+			if err = json.Unmarshal(data, ev); err == nil {
+				return ev
+			}
+		}
+
+		stderrLog.Println("ERROR read offline error event:", err)
+		os.Remove(q.files[0])
+		q.files = q.files[1:]
+	}
+
+	return nil
+}
+
+// pop removes the oldest event
+func (q *errorEventQueue) pop() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.dir == "" {
+		if len(q.events) > 0 {
+			q.events = q.events[1:]
+		}
+		return
+	}
+
+	if len(q.files) > 0 {
+		os.Remove(q.files[0])
+		q.files = q.files[1:]
+	}
+}
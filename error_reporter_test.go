@@ -0,0 +1,121 @@
+package gobase
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestErrorReporter(t *testing.T, config ErrorReporterConfig) (*ErrorReporter, *ErrorReportReceiver) {
+	t.Helper()
+
+	receiver, err := NewErrorReportReceiver("127.0.0.1:0", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { receiver.Close() })
+
+	config.DSN = receiver.DSN()
+	config.RetryInterval = time.Hour
+
+	reporter, err := NewErrorReporter(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { reporter.Close() })
+
+	return reporter, receiver
+}
+
+func TestErrorReporterSampleRate(t *testing.T) {
+	none, all := 0.0, 1.0
+
+	for _, test := range []struct {
+		rate *float64
+		want int
+	}{{nil, 3}, {&all, 3}, {&none, 0}} {
+		reporter, receiver := newTestErrorReporter(t, ErrorReporterConfig{SampleRate: test.rate})
+
+		logger := NewRecordingLogger()
+		logger.SetErrorReporter(reporter)
+		for i := 0; i < 3; i++ {
+			logger.Error("db", "query failed")
+		}
+		reporter.Flush()
+
+		if n := len(receiver.Events()); n != test.want {
+			t.Errorf("sample rate %v: reported %d events, want %d", test.rate, n, test.want)
+		}
+	}
+}
+
+func TestErrorReporterLevel(t *testing.T) {
+	reporter, _ := newTestErrorReporter(t, ErrorReporterConfig{})
+	if reporter.level != LevelError {
+		t.Errorf("default level %v", reporter.level)
+	}
+
+	level := LevelEmergency
+	reporter, _ = newTestErrorReporter(t, ErrorReporterConfig{Level: &level})
+	if reporter.level != LevelEmergency {
+		t.Errorf("level %v, want %v", reporter.level, LevelEmergency)
+	}
+
+	logger, err := NewLoggerFromConfig("test", map[string]string{"LOG_test": "none;report-dsn=http://key@127.0.0.1:1/1;report-level=emerg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logger.Close()
+
+	if r := errorReporterOf(logger).reporter; r == nil || r.level != LevelEmergency {
+		t.Errorf("report-level=emerg is not configured")
+	}
+}
+
+func TestReportPanicIsRedacted(t *testing.T) {
+	reporter, receiver := newTestErrorReporter(t, ErrorReporterConfig{})
+
+	logger := NewRecordingLogger()
+	logger.SetErrorReporter(reporter)
+
+	func() {
+		defer LogPanic(logger, "test")
+		panic("call +12345678901 failed")
+	}()
+	reporter.Flush()
+
+	events := receiver.Events()
+	if len(events) != 1 {
+		t.Fatalf("reported %d events, want 1", len(events))
+	}
+	if ev := events[0]; strings.Contains(ev.Message, "12345678901") || strings.Contains(ev.Exception.Values[0].Value, "12345678901") {
+		t.Errorf("panic value not redacted: %q, %q", ev.Message, ev.Exception.Values[0].Value)
+	}
+}
+
+func TestErrorReporterEventsNotLostOnClose(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		reporter, receiver := newTestErrorReporter(t, ErrorReporterConfig{})
+
+		logger := NewRecordingLogger()
+		logger.SetErrorReporter(reporter)
+
+		const events = 20
+		var wg sync.WaitGroup
+		for j := 0; j < events; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				logger.Error("db", "query failed")
+			}()
+		}
+		reporter.Close()
+		wg.Wait()
+
+		// Events are sent, or kept offline after Close
+		if n := len(receiver.Events()) + reporter.offline.len(); n != events {
+			t.Fatalf("%d of %d events sent or kept", n, events)
+		}
+	}
+}
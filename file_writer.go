@@ -0,0 +1,334 @@
+package gobase
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-faster/errors"
+	"gopkg.in/Graylog2/go-gelf.v2/gelf"
+)
+
+type FileWriterConfig struct {
+	Path string
+	// Format is "json" (GELF JSON-lines, default) or "text" (ConsoleWriter output with full RFC3339 time, without colours)
+	Format string
+	// MaxSize rotates file when it grows bigger (bytes), 0 disables
+	MaxSize int64
+	// MaxAge rotates file opened longer ago, 0 disables
+	MaxAge time.Duration
+	// MaxBackups is number of rotated files kept, older are deleted, 0 keeps all
+	MaxBackups int
+	// Compress rotated files with gzip
+	Compress bool
+	// SyncLevel: file is fsynced after messages with this or more severe level, so that crash logs survive.
+	// LevelCritical if nil.
+	SyncLevel *Level
+}
+
+// FileWriter appends messages to a local file, rotating it by size and age
+type FileWriter struct {
+	mu        sync.Mutex
+	config    FileWriterConfig
+	formatter MessageWriter
+	file      *os.File
+	size      int64
+	openedAt  time.Time
+	syncLevel Level
+	compress  sync.WaitGroup
+	// stopReopen stops ReopenOnSIGHUP started for the writer by NewLoggerFromConfig
+	stopReopen context.CancelFunc
+}
+
+func NewFileWriter(config FileWriterConfig) (*FileWriter, error) {
+	if config.Path == "" {
+		return nil, errors.New("No path specified for file writer")
+	}
+
+	w := &FileWriter{config: config, syncLevel: LevelCritical}
+	if config.SyncLevel != nil {
+		w.syncLevel = *config.SyncLevel
+	}
+
+	switch config.Format {
+	case "", "json":
+		w.formatter = NewJSONWriter(fileWriterOutput{w})
+	case "text", "console":
+		w.formatter = &ConsoleWriter{out: fileWriterOutput{w}, timeFormat: fileTextTimeFormat}
+	default:
+		return nil, errors.Errorf("Invalid file writer format '%s'", config.Format)
+	}
+
+	f, size, err := openLogFile(config.Path)
+	if err != nil {
+		return nil, err
+	}
+	w.setFile(f, size)
+
+	return w, nil
+}
+
+// fileWriterOutput is where formatter writes, called with FileWriter.mu held
+type fileWriterOutput struct {
+	w *FileWriter
+}
+
+func (o fileWriterOutput) Write(p []byte) (int, error) {
+	n, err := o.w.file.Write(p)
+	o.w.size += int64(n)
+	return n, err
+}
+
+// openLogFile opens file at path for appending, returns its size
+func openLogFile(path string) (*os.File, int64, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, 0, err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+
+	return f, stat.Size(), nil
+}
+
+func (w *FileWriter) setFile(f *os.File, size int64) {
+	w.file = f
+	w.size = size
+	w.openedAt = time.Now()
+}
+
+func (w *FileWriter) WriteMessage(m *gelf.Message) error {
+	_, err := w.writeMessage(m)
+	return err
+}
+
+func (w *FileWriter) writeMessage(m *gelf.Message) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return 0, errors.New("file writer is closed")
+	}
+
+	if w.needsRotation() {
+		if err := w.rotate(); err != nil {
+			return 0, errors.Wrap(err, "rotate")
+		}
+	}
+
+	n, err := writeMessage(w.formatter, m)
+	if err != nil {
+		return n, err
+	}
+
+	if Level(m.Level) <= w.syncLevel {
+		return n, w.file.Sync()
+	}
+
+	return n, nil
+}
+
+func (w *FileWriter) needsRotation() bool {
+	if w.config.MaxSize > 0 && w.size >= w.config.MaxSize {
+		return true
+	}
+	return w.config.MaxAge > 0 && time.Since(w.openedAt) >= w.config.MaxAge
+}
+
+// Rotate renames current file to a timestamped backup and opens a new one
+func (w *FileWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return errors.New("file writer is closed")
+	}
+
+	return w.rotate()
+}
+
+// rotate keeps writing to the current file if a new one can't be opened
+func (w *FileWriter) rotate() error {
+	backup := w.config.Path + "." + time.Now().Format(fileBackupTimeFormat)
+	for i := 1; fileExists(backup) || fileExists(backup+".gz"); i++ {
+		backup = fmt.Sprintf("%s.%s-%d", w.config.Path, time.Now().Format(fileBackupTimeFormat), i)
+	}
+	if err := os.Rename(w.config.Path, backup); err != nil {
+		return err
+	}
+
+	f, size, err := openLogFile(w.config.Path)
+	if err != nil {
+		if errRename := os.Rename(backup, w.config.Path); errRename != nil {
+			// Messages go to the backup until the next successful rotation.
+			// Not logged: the logger may write to this writer, whose lock is held.
+			stderrLog.Printf("ERROR FileWriter: rename %s back: %s", backup, errRename)
+		}
+		return err
+	}
+
+	if err := w.file.Close(); err != nil {
+		stderrLog.Printf("ERROR FileWriter: close %s: %s", backup, err)
+	}
+	w.setFile(f, size)
+
+	w.compress.Add(1)
+	go func() {
+		defer w.compress.Done()
+
+		if w.config.Compress {
+			if err := compressFile(backup); err != nil {
+				LogErrorf("FileWriter: compress %s: %s", backup, err)
+			}
+		}
+
+		w.removeOldBackups()
+	}()
+
+	return nil
+}
+
+// Reopen opens the file again at the same path, after it was moved by external logrotate.
+// The old file is kept if the new one can't be opened.
+func (w *FileWriter) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return errors.New("file writer is closed")
+	}
+
+	f, size, err := openLogFile(w.config.Path)
+	if err != nil {
+		return err
+	}
+
+	w.file.Close()
+	w.setFile(f, size)
+
+	return nil
+}
+
+func (w *FileWriter) Close() error {
+	if w.stopReopen != nil {
+		w.stopReopen()
+	}
+
+	w.mu.Lock()
+	if w.file == nil {
+		w.mu.Unlock()
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil // no rotation starts compression after this
+	w.mu.Unlock()
+
+	w.compress.Wait()
+
+	return err
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+
+	if _, err := io.Copy(gz, src); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+
+	if err := dst.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+func (w *FileWriter) removeOldBackups() {
+	if w.config.MaxBackups <= 0 {
+		return
+	}
+
+	backups, err := fileBackups(w.config.Path)
+	if err != nil {
+		return
+	}
+
+	// Backup names end with timestamp, so sorting by name sorts by age.
+	// Uncompressed backups being compressed right now have a .gz twin, count them once.
+	names := map[string]bool{}
+	for _, b := range backups {
+		names[strings.TrimSuffix(b, ".gz")] = true
+	}
+
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	for i := 0; i < len(sorted)-w.config.MaxBackups; i++ {
+		os.Remove(sorted[i])
+		os.Remove(sorted[i] + ".gz")
+	}
+}
+
+// Rotated files are named path.2006-01-02T15-04-05.000, with "-N" suffix if the name was taken and ".gz" if compressed
+const fileBackupTimeFormat = "2006-01-02T15-04-05.000"
+
+var fileBackupSuffix = regexp.MustCompile(`^\.\d{4}-\d{2}-\d{2}T\d{2}-\d{2}-\d{2}\.\d{3}(-\d+)?(\.gz)?$`)
+
+// fileBackups returns rotated backups of the file at path, other files with the same prefix are left out
+func fileBackups(path string) ([]string, error) {
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return nil, err
+	}
+
+	backups := matches[:0]
+	for _, m := range matches {
+		if fileBackupSuffix.MatchString(strings.TrimPrefix(m, path)) {
+			backups = append(backups, m)
+		}
+	}
+
+	return backups, nil
+}
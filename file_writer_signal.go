@@ -0,0 +1,33 @@
+//go:build unix
+
+package gobase
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// ReopenOnSIGHUP reopens files on SIGHUP until ctx is done (external logrotate moved them)
+func ReopenOnSIGHUP(ctx context.Context, writers ...*FileWriter) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(c)
+
+		for {
+			select {
+			case <-c:
+				for _, w := range writers {
+					if err := w.Reopen(); err != nil {
+						LogErrorf("FileWriter: reopen %s: %s", w.config.Path, err)
+					}
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
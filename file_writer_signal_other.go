@@ -0,0 +1,11 @@
+//go:build !unix
+
+package gobase
+
+import (
+	"context"
+)
+
+// ReopenOnSIGHUP does nothing on platforms without SIGHUP
+func ReopenOnSIGHUP(ctx context.Context, writers ...*FileWriter) {
+}
@@ -0,0 +1,40 @@
+//go:build unix
+
+package gobase
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestLoggerFromConfigReopensFileOnSIGHUP(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+
+	logger, err := NewLoggerFromConfig("test", map[string]string{"LOG_test": "file;path=" + path + ";reopen=sighup;stderr=none"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logger.Close()
+
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	syscall.Kill(os.Getpid(), syscall.SIGHUP)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !fileExists(path) {
+		if time.Now().After(deadline) {
+			t.Fatal("file is not reopened on SIGHUP")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	logger.Info("test", "after reopen")
+	if data := readTestFile(t, path); !strings.Contains(data, "after reopen") {
+		t.Errorf("unexpected reopened file: %s", data)
+	}
+}
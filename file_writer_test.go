@@ -0,0 +1,148 @@
+package gobase
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/Graylog2/go-gelf.v2/gelf"
+)
+
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestFileWriterRemovesOnlyBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	for _, name := range []string{"app.log.bak", "app.log.2020-01-01", "app.log.old.gz"} {
+		if err := os.WriteFile(filepath.Join(filepath.Dir(path), name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w, err := NewFileWriter(FileWriterConfig{Path: path, MaxBackups: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := w.WriteMessage(&gelf.Message{Short: "message"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Close()
+
+	backups, _ := fileBackups(path)
+	if len(backups) != 1 {
+		t.Errorf("backups kept: %v", backups)
+	}
+	for _, name := range []string{"app.log.bak", "app.log.2020-01-01", "app.log.old.gz"} {
+		if !fileExists(filepath.Join(filepath.Dir(path), name)) {
+			t.Errorf("unrelated file %s removed", name)
+		}
+	}
+}
+
+func TestFileWriterReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+
+	w, err := NewFileWriter(FileWriterConfig{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	w.WriteMessage(&gelf.Message{Short: "before"})
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	w.WriteMessage(&gelf.Message{Short: "after"})
+
+	if moved := readTestFile(t, path+".1"); !strings.Contains(moved, "before") || strings.Contains(moved, "after") {
+		t.Errorf("unexpected moved file: %s", moved)
+	}
+	if reopened := readTestFile(t, path); !strings.Contains(reopened, "after") {
+		t.Errorf("unexpected reopened file: %s", reopened)
+	}
+}
+
+func TestFileWriterSyncLevel(t *testing.T) {
+	dir := t.TempDir()
+
+	w, err := NewFileWriter(FileWriterConfig{Path: filepath.Join(dir, "default.log")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if w.syncLevel != LevelCritical {
+		t.Errorf("default sync level %v", w.syncLevel)
+	}
+
+	level := LevelEmergency
+	w, err = NewFileWriter(FileWriterConfig{Path: filepath.Join(dir, "emerg.log"), SyncLevel: &level})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if w.syncLevel != LevelEmergency {
+		t.Errorf("sync level %v, want %v", w.syncLevel, LevelEmergency)
+	}
+}
+
+func TestFileWriterTextHasDate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	w, err := NewFileWriter(FileWriterConfig{Path: path, Format: "text"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	w.WriteMessage(&gelf.Message{Short: "test", Full: "message", TimeUnix: float64(ts.Unix())})
+	w.Close()
+
+	line := readTestFile(t, path)
+	if !strings.HasPrefix(line, ts.Local().Format(fileTextTimeFormat)+" ") {
+		t.Errorf("line has no full time: %q", line)
+	}
+}
+
+func TestFileWriterRotateWhileClosing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	w, err := NewFileWriter(FileWriterConfig{Path: path, Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			w.WriteMessage(&gelf.Message{Short: "message"})
+			w.Rotate()
+		}
+	}()
+
+	time.Sleep(time.Millisecond)
+	if err := w.Close(); err != nil {
+		t.Error(err)
+	}
+	<-done
+
+	if err := w.Rotate(); err == nil {
+		t.Error("closed writer rotated")
+	}
+}
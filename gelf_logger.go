@@ -5,7 +5,8 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"dario.cat/mergo"
@@ -13,12 +14,44 @@ import (
 	"gopkg.in/Graylog2/go-gelf.v2/gelf"
 )
 
+// MessageWriter is a sink that delivers complete GELF messages (Graylog, stdout, file, ...)
+type MessageWriter interface {
+	WriteMessage(m *gelf.Message) error
+	Close() error
+}
+
+// GelfLogger is safe for concurrent use: fields are guarded by mutex,
+// every message gets its own copy of merged fields.
 type GelfLogger struct {
 	Logger
-	writer             gelf.Writer
+	writer             MessageWriter
 	facility, hostname string
+	mu                 sync.RWMutex
 	fields             map[string]any
-	stderr             bool
+	level              Level                         // messages with greater (less severe) level are not sent, unless overridden in Levels registry
+	stderrLevel        Level                         // messages with this or lower level are also printed to stderr, -1 disables
+	redactor           *Redactor                     // nil disables redaction
+	sampler            atomic.Pointer[Sampler]       // nil disables sampling
+	reporter           atomic.Pointer[ErrorReporter] // nil disables error reporting
+	stats              *sinkStats
+}
+
+func newGelfLogger(writer MessageWriter, facility, selfHostname string) *GelfLogger {
+	stats := newSinkStats(writer)
+	if async, ok := writer.(asyncSink); ok {
+		async.setSinkStats(stats)
+	}
+
+	return &GelfLogger{
+		writer:      writer,
+		facility:    facility,
+		hostname:    selfHostname,
+		fields:      map[string]any{},
+		level:       LevelDebug,
+		stderrLevel: -1,
+		redactor:    DefaultRedactor,
+		stats:       stats,
+	}
 }
 
 func NewGelfLogger(facility, graylogAddr, selfHostname string) Logger {
@@ -30,13 +63,8 @@ func NewGelfLogger(facility, graylogAddr, selfHostname string) Logger {
 
 	gelfWriter.Facility = facility
 
-	logger := &GelfLogger{
-		writer:   gelfWriter,
-		facility: facility,
-		hostname: selfHostname,
-		stderr:   true,
-		fields:   map[string]any{},
-	}
+	logger := newGelfLogger(gelfWriter, facility, selfHostname)
+	logger.stderrLevel = LevelError
 
 	log.Printf("Logging errors to stderr, full logging to  graylog @%s", graylogAddr)
 
@@ -44,58 +72,224 @@ func NewGelfLogger(facility, graylogAddr, selfHostname string) Logger {
 }
 
 func (logger *GelfLogger) Close() error {
+	if r := logger.reporter.Load(); r != nil {
+		r.Close()
+	}
 	return logger.writer.Close()
 }
 
-func (logger *GelfLogger) AddRequestID(requestUid string, fields ...map[string]any) Logger {
-	if oldId, ok := logger.fields["request_uid"]; ok {
-		requestUid = oldId.(string) + "/" + requestUid
+// Flush sends messages buffered by the sink (e.g. OTLPWriter batch), if it buffers them, and queued error events
+func (logger *GelfLogger) Flush() {
+	if f, ok := logger.writer.(interface{ Flush() }); ok {
+		f.Flush()
 	}
+	if r := logger.reporter.Load(); r != nil {
+		r.Flush()
+	}
+}
+
+// SetErrorReporter makes this logger and loggers made by AddRequestID after the call report panics (see LogPanic)
+// and messages of reporter level as error events, "event_id" field of the message links it to the event.
+// Reporter is closed by Close.
+func (logger *GelfLogger) SetErrorReporter(r *ErrorReporter) {
+	logger.reporter.Store(r)
+}
+
+// errorReporter returns reporter, facility and hostname to report panics with
+func (logger *GelfLogger) errorReporter() loggerReporter {
+	return loggerReporter{reporter: logger.reporter.Load(), facility: logger.facility, hostname: logger.hostname, redactor: logger.redactor}
+}
 
-	newFields := map[string]any{}
-	mergo.Merge(&newFields, logger.fields, mergo.WithOverride)
+func (logger *GelfLogger) AddRequestID(requestUid string, fields ...map[string]any) Logger {
+	newFields := logger.mergedFields(fields...)
 
-	for _, v := range fields {
-		mergo.Merge(&newFields, v, mergo.WithOverride)
+	if oldId, ok := newFields["request_uid"].(string); ok && oldId != "" {
+		requestUid = oldId + "/" + requestUid
 	}
 
 	newFields["request_uid"] = requestUid
 
-	return &GelfLogger{
-		writer:   logger.writer,
-		facility: logger.facility,
-		hostname: logger.hostname,
-		stderr:   logger.stderr,
-		fields:   newFields,
+	child := &GelfLogger{
+		writer:      logger.writer,
+		facility:    logger.facility,
+		hostname:    logger.hostname,
+		fields:      newFields,
+		level:       logger.level,
+		stderrLevel: logger.stderrLevel,
+		redactor:    logger.redactor,
+		stats:       logger.stats,
+	}
+	child.sampler.Store(logger.sampler.Load())
+	child.reporter.Store(logger.reporter.Load())
+
+	return child
+}
+
+// mergedFields returns a new map with logger fields overridden by extras
+func (logger *GelfLogger) mergedFields(extras ...map[string]any) map[string]any {
+	logger.mu.RLock()
+	defer logger.mu.RUnlock()
+
+	merged := make(map[string]any, len(logger.fields))
+	mergo.Merge(&merged, logger.fields, mergo.WithOverride)
+
+	for _, v := range extras {
+		mergo.Merge(&merged, v, mergo.WithOverride)
 	}
+
+	return merged
+}
+
+// Fields returns a copy of fields attached to every message of this logger
+func (logger *GelfLogger) Fields() map[string]any {
+	return logger.mergedFields()
 }
 
 func (logger *GelfLogger) SetField(key string, value any) {
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+
 	logger.fields[key] = value
 }
 
 func (logger *GelfLogger) SetFields(newFields map[string]any) {
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+
 	mergo.Merge(&logger.fields, newFields, mergo.WithOverride)
 }
 
-func (logger *GelfLogger) Message(level int32, kind string, message string, fields ...map[string]any) bool {
+// Enabled tells if message of given level and kind would be sent, use it to skip expensive formatting
+func (logger *GelfLogger) Enabled(level Level, kind string) bool {
+	return level <= Levels.Effective(kind, logger.level)
+}
 
-	messageFields := logger.fields
+func (logger *GelfLogger) Message(level Level, kind string, message string, fields ...map[string]any) bool {
+	if !logger.accept(level, kind, message, false) {
+		return true
+	}
+	return logger.message(1, level, kind, message, fields...)
+}
+
+// accept tells if message passes level and sampling checks, text is printf format (isFormat) or message text
+func (logger *GelfLogger) accept(level Level, kind, text string, isFormat bool) bool {
+	if !logger.Enabled(level, kind) {
+		return false
+	}
+
+	s := logger.sampler.Load()
+	if s == nil {
+		return true
+	}
 
-	if len(fields) > 0 {
-		messageFields = make(map[string]any)
+	template := text
+	if !isFormat {
+		template = messageTemplate(text)
+	}
 
-		mergo.Merge(&messageFields, logger.fields, mergo.WithOverride)
+	if !s.Allow(level, kind, template) {
+		logger.stats.dropped.Add(1)
+		return false
+	}
+	return true
+}
 
-		for _, callExtraFields := range fields {
-			mergo.Merge(&messageFields, callExtraFields, mergo.WithOverride)
+// SetSampler enables sampling of repeated messages for this logger and loggers made by AddRequestID after the call.
+// Summaries of suppressed messages are sent by this logger.
+func (logger *GelfLogger) SetSampler(s *Sampler) {
+	if s != nil {
+		s.mu.Lock()
+		s.onSummary = func(level Level, kind, template string, suppressed int) {
+			logger.message(0, level, kind, fmt.Sprintf("suppressed %d similar messages", suppressed), map[string]any{
+				"sampled_template": template,
+				"suppressed":       suppressed,
+			})
 		}
+		s.mu.Unlock()
 	}
+	logger.sampler.Store(s)
+}
+
+func (logger *GelfLogger) Debug(kind, message string, keyvals ...any) {
+	logger.logKeyvals(LevelDebug, kind, message, keyvals)
+}
+
+func (logger *GelfLogger) Debugf(kind, format string, args ...any) {
+	logger.logf(LevelDebug, kind, format, args)
+}
+
+func (logger *GelfLogger) Info(kind, message string, keyvals ...any) {
+	logger.logKeyvals(LevelInfo, kind, message, keyvals)
+}
+
+func (logger *GelfLogger) Infof(kind, format string, args ...any) {
+	logger.logf(LevelInfo, kind, format, args)
+}
+
+func (logger *GelfLogger) Warn(kind, message string, keyvals ...any) {
+	logger.logKeyvals(LevelWarning, kind, message, keyvals)
+}
+
+func (logger *GelfLogger) Warnf(kind, format string, args ...any) {
+	logger.logf(LevelWarning, kind, format, args)
+}
+
+func (logger *GelfLogger) Error(kind, message string, keyvals ...any) {
+	logger.logKeyvals(LevelError, kind, message, keyvals)
+}
+
+func (logger *GelfLogger) Errorf(kind, format string, args ...any) {
+	logger.logf(LevelError, kind, format, args)
+}
+
+func (logger *GelfLogger) Critical(kind, message string, keyvals ...any) {
+	logger.logKeyvals(LevelCritical, kind, message, keyvals)
+}
+
+func (logger *GelfLogger) Criticalf(kind, format string, args ...any) {
+	logger.logf(LevelCritical, kind, format, args)
+}
 
-	if level <= gelf.LOG_ERR {
+func (logger *GelfLogger) Start(kind, message string, keyvals ...any) *Operation {
+	return newOperation(logger, kind, message, keyvals)
+}
+
+// logKeyvals and logf are called only from level helpers, user code is 2 frames above message()
+func (logger *GelfLogger) logKeyvals(level Level, kind, message string, keyvals []any) {
+	if !logger.accept(level, kind, message, false) {
+		return
+	}
+	logger.message(2, level, kind, message, KeyvalsToFields(keyvals...))
+}
+
+func (logger *GelfLogger) logf(level Level, kind, format string, args []any) {
+	if !logger.accept(level, kind, format, true) {
+		return
+	}
+	logger.message(2, level, kind, fmt.Sprintf(format, args...))
+}
+
+// message sends the message (level and sampling are checked by caller),
+// callerSkip is number of frames between message() and the user code that logs
+func (logger *GelfLogger) message(callerSkip int, level Level, kind string, message string, fields ...map[string]any) bool {
+	messageFields := logger.mergedFields(fields...)
+
+	addCallerFields(messageFields, callerSkip+1)
+
+	if logger.redactor != nil {
+		messageFields = logger.redactor.RedactFields(messageFields)
+		message = logger.redactor.RedactString(message)
+	} else {
+		// Redactor converts errors to text too
+		for k, v := range messageFields {
+			messageFields[k] = errorValuesText(v)
+		}
+	}
+
+	if level <= logger.stderrLevel {
 		stdErrMessage := fmt.Sprintf("%s: %s\n", kind, message)
 
-		if ruid, ok := logger.fields["request_uid"].(string); ok && ruid != "" {
+		if ruid, ok := messageFields["request_uid"].(string); ok && ruid != "" {
 			stdErrMessage = fmt.Sprintf("[%s] %s", ruid, stdErrMessage)
 		}
 
@@ -108,28 +302,50 @@ func (logger *GelfLogger) Message(level int32, kind string, message string, fiel
 		Short:    kind,
 		Full:     message,
 		TimeUnix: float64(time.Now().UnixNano()) / float64(time.Second),
-		Level:    level,
+		Level:    int32(level),
 		Extra:    messageFields,
 		Facility: logger.facility,
 	}
 
-	err := logger.writer.WriteMessage(m)
+	// Panics are reported by reportPanic, which sets event_id
+	if _, reported := messageFields["event_id"]; !reported {
+		if r := logger.reporter.Load(); r != nil {
+			if id := r.reportMessage(m, callerSkip+1); id != "" {
+				messageFields["event_id"] = id
+			}
+		}
+	}
+
+	size, err := writeMessage(logger.writer, m)
 	if err == nil {
+		if recovered, failedFor := logger.stats.written(size); recovered {
+			logger.message(0, LevelWarning, "logger", fmt.Sprintf("logger sink recovered after failing for %s", failedFor.Round(time.Millisecond)), map[string]any{
+				"sink": logger.stats.sink,
+			})
+		}
 		return true
 	}
 
-	log.Println("ERROR WriteMessage GELF in GelfWriterLogging.Message:", err.Error())
-	if data, err := json.MarshalIndent(fields, "", "    "); err != nil {
-		log.Println("WARN log not sent", err)
+	logger.stats.writeFailed(err)
+	if errors.Is(err, ErrMessageDropped) {
+		// Sink drops messages on purpose (queue is full), printing each of them would flood stderr
+		return false
+	}
+
+	stderrLog.Println("ERROR WriteMessage GELF in GelfWriterLogging.Message:", err.Error())
+	// Fields as sent, redacted
+	if data, err := json.MarshalIndent(messageFields, "", "    "); err != nil {
+		stderrLog.Println("WARN log not sent", err)
 	} else {
-		log.Println("WARN log not sent", string(data))
+		stderrLog.Println("WARN log not sent", string(data))
 	}
 
 	return false
 }
 
+// Write logs each line of p as a message of kind "stdout", level is detected from prefix (see LogBridge), LevelInfo by default
 func (logger *GelfLogger) Write(p []byte) (int, error) {
-	if logger.Message(gelf.LOG_INFO, "stdout", strings.Trim(string(p), "\n ")) {
+	if writeLogLines(logger, "", LevelInfo, p) {
 		return len(p), nil
 	} else {
 		return 0, errors.New("logger.Message() returned false")
@@ -137,6 +353,6 @@ func (logger *GelfLogger) Write(p []byte) (int, error) {
 }
 
 func (l *GelfLogger) SetAsDefault() Logger {
-	defaultLogger = l
+	setDefaultLogger(l)
 	return l
 }
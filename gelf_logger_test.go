@@ -0,0 +1,126 @@
+package gobase
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Run with -race: loggers are shared by concurrent gRPC handlers and goroutines
+
+func TestGelfLoggerConcurrentFields(t *testing.T) {
+	recorder := &RecordingWriter{}
+	logger := newGelfLogger(recorder, "test", "test")
+
+	const workers, iterations = 8, 200
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(3)
+
+		go func() {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				logger.SetField(fmt.Sprintf("field_%d", w), i)
+				logger.SetFields(map[string]any{"shared": i})
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				logger.Message(LevelInfo, "test", "message", map[string]any{"i": i})
+				logger.Info("test", "helper", "i", i)
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				child := logger.AddRequestID(fmt.Sprintf("req%d", i), map[string]any{"child": w})
+				child.SetField("child_field", i)
+				child.Warn("test", "child message")
+				_ = child.Fields()
+			}
+		}()
+	}
+	wg.Wait()
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	if want := workers * iterations * 3; len(recorder.messages) != want {
+		t.Fatalf("recorded %d messages, want %d", len(recorder.messages), want)
+	}
+}
+
+func TestGelfLoggerMessageFieldsAreCopies(t *testing.T) {
+	recorder := &RecordingWriter{}
+	logger := newGelfLogger(recorder, "test", "test")
+
+	extras := map[string]any{"a": 1}
+	logger.Message(LevelInfo, "test", "first", extras)
+	logger.SetField("b", 2)
+	extras["a"] = 3
+
+	m := recorder.messages[0]
+	if m.Fields["a"] != 1 {
+		t.Errorf("message field changed after logging: a=%v", m.Fields["a"])
+	}
+	if _, ok := m.Fields["b"]; ok {
+		t.Errorf("field set after logging appeared in message")
+	}
+}
+
+func TestGelfLoggerAddRequestIDNested(t *testing.T) {
+	logger := newGelfLogger(&RecordingWriter{}, "test", "test")
+	logger.SetField("service", "bot")
+
+	child := logger.AddRequestID("a").AddRequestID("b")
+	child.SetField("user_id", 1)
+
+	fields := child.Fields()
+	if fields["request_uid"] != "a/b" || fields["service"] != "bot" {
+		t.Errorf("unexpected child fields %v", fields)
+	}
+	if _, ok := logger.Fields()["user_id"]; ok {
+		t.Errorf("child field leaked to parent logger")
+	}
+}
+
+func TestGelfLoggerConcurrentSetters(t *testing.T) {
+	logger := newGelfLogger(&RecordingWriter{}, "test", "test")
+	defer setDefaultLogger(DefaultLogger())
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			logger.SetSampler(NewSampler(time.Second, LevelError, SamplingPolicy{First: 10, Thereafter: 10}))
+			logger.SetSampler(nil)
+			logger.SetErrorReporter(nil)
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			logger.Error("test", "message", "i", i)
+			logger.AddRequestID("req").Warn("test", "child")
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			logger.SetAsDefault()
+			DefaultLogger().Enabled(LevelInfo, "test")
+			LoggerFrom(nil)
+		}
+	}()
+
+	wg.Wait()
+}
@@ -0,0 +1,294 @@
+package gobase
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-faster/errors"
+	"gopkg.in/Graylog2/go-gelf.v2/gelf"
+)
+
+// Incomplete chunked UDP messages are dropped after this time, as Graylog does
+const gelfChunksTimeout = 5 * time.Second
+
+// GelfReceiver is a GELF server for tests and local development without Graylog.
+// It listens on the same port for TCP (null-delimited JSON) and UDP (chunked, gzip or zlib compressed) messages.
+type GelfReceiver struct {
+	handler func(m *gelf.Message)
+
+	tcp net.Listener
+	udp net.PacketConn
+	wg  sync.WaitGroup
+
+	mu       sync.Mutex
+	conns    map[net.Conn]struct{}
+	messages []*gelf.Message
+	closed   bool
+}
+
+// NewGelfReceiver listens on addr ("127.0.0.1:0" picks free port, see Addr).
+// handler is called for every received message, concurrently for different connections;
+// if it is nil, messages are kept and returned by Messages.
+func NewGelfReceiver(addr string, handler func(m *gelf.Message)) (*GelfReceiver, error) {
+	tcp, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, errors.Wrap(err, "listen tcp")
+	}
+
+	udp, err := net.ListenPacket("udp", tcp.Addr().String())
+	if err != nil {
+		tcp.Close()
+		return nil, errors.Wrap(err, "listen udp")
+	}
+
+	r := &GelfReceiver{
+		handler: handler,
+		tcp:     tcp,
+		udp:     udp,
+		conns:   map[net.Conn]struct{}{},
+	}
+
+	r.wg.Add(2)
+	go r.acceptTCP()
+	go r.readUDP()
+
+	return r, nil
+}
+
+// Addr returns "host:port" the receiver listens on, for gelf.NewTCPWriter and gelf.NewUDPWriter
+func (r *GelfReceiver) Addr() string {
+	return r.tcp.Addr().String()
+}
+
+// Messages returns received messages, when receiver was created without handler
+func (r *GelfReceiver) Messages() []*gelf.Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]*gelf.Message(nil), r.messages...)
+}
+
+func (r *GelfReceiver) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	for conn := range r.conns {
+		conn.Close()
+	}
+	r.mu.Unlock()
+
+	err := r.tcp.Close()
+	r.udp.Close()
+	r.wg.Wait()
+
+	return err
+}
+
+func (r *GelfReceiver) receive(m *gelf.Message) {
+	if r.handler != nil {
+		r.handler(m)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.messages = append(r.messages, m)
+}
+
+func (r *GelfReceiver) acceptTCP() {
+	defer r.wg.Done()
+
+	for {
+		conn, err := r.tcp.Accept()
+		if err != nil {
+			return
+		}
+
+		r.mu.Lock()
+		if r.closed {
+			r.mu.Unlock()
+			conn.Close()
+			return
+		}
+		r.conns[conn] = struct{}{}
+		r.mu.Unlock()
+
+		r.wg.Add(1)
+		go r.readTCP(conn)
+	}
+}
+
+func (r *GelfReceiver) readTCP(conn net.Conn) {
+	defer r.wg.Done()
+	defer func() {
+		r.mu.Lock()
+		delete(r.conns, conn)
+		r.mu.Unlock()
+		conn.Close()
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.IndexByte(data, 0); i >= 0 {
+			return i + 1, data[:i], nil
+		}
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	})
+
+	for scanner.Scan() {
+		if m, err := DecodeGelfMessage(scanner.Bytes()); err == nil {
+			r.receive(m)
+		} else {
+			stderrLog.Println("GelfReceiver: tcp message from", conn.RemoteAddr(), "dropped:", err)
+		}
+	}
+}
+
+type gelfChunks struct {
+	parts    [][]byte
+	received int
+	started  time.Time
+}
+
+func (r *GelfReceiver) readUDP() {
+	defer r.wg.Done()
+
+	pending := map[string]*gelfChunks{}
+	buf := make([]byte, 65536)
+
+	for {
+		n, from, err := r.udp.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		packet := buf[:n]
+
+		if n < 12 || packet[0] != 0x1e || packet[1] != 0x0f {
+			r.receiveUDP(from, packet)
+			continue
+		}
+
+		// Chunk header: magic (2), message ID (8), sequence number (1), sequence count (1)
+		id, seq, count := string(packet[2:10]), int(packet[10]), int(packet[11])
+		if count == 0 || count > 128 || seq >= count {
+			continue
+		}
+
+		for pendingID, chunks := range pending {
+			if time.Since(chunks.started) > gelfChunksTimeout {
+				delete(pending, pendingID)
+			}
+		}
+
+		chunks, ok := pending[id]
+		if !ok {
+			chunks = &gelfChunks{parts: make([][]byte, count), started: time.Now()}
+			pending[id] = chunks
+		}
+		if len(chunks.parts) != count || chunks.parts[seq] != nil {
+			continue
+		}
+
+		chunks.parts[seq] = append([]byte(nil), packet[12:]...)
+		chunks.received++
+
+		if chunks.received == count {
+			delete(pending, id)
+			r.receiveUDP(from, bytes.Join(chunks.parts, nil))
+		}
+	}
+}
+
+func (r *GelfReceiver) receiveUDP(from net.Addr, data []byte) {
+	if m, err := DecodeGelfMessage(data); err == nil {
+		r.receive(m)
+	} else {
+		stderrLog.Println("GelfReceiver: udp message from", from, "dropped:", err)
+	}
+}
+
+// DecodeGelfMessage parses GELF JSON, gzip or zlib compressed or not.
+// Additional fields are returned in Extra without "_" prefix.
+func DecodeGelfMessage(data []byte) (*gelf.Message, error) {
+	var reader io.Reader = bytes.NewReader(data)
+
+	switch {
+	case len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b:
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, errors.Wrap(err, "gzip")
+		}
+		reader = gz
+	case len(data) > 2 && data[0] == 0x78 && (int(data[0])*256+int(data[1]))%31 == 0:
+		zr, err := zlib.NewReader(reader)
+		if err != nil {
+			return nil, errors.Wrap(err, "zlib")
+		}
+		reader = zr
+	}
+
+	var fields map[string]any
+	decoder := json.NewDecoder(reader)
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, errors.Wrap(err, "json")
+	}
+
+	m := &gelf.Message{Extra: map[string]any{}}
+
+	for k, v := range fields {
+		switch k {
+		case "version":
+			m.Version, _ = v.(string)
+		case "host":
+			m.Host, _ = v.(string)
+		case "short_message":
+			m.Short, _ = v.(string)
+		case "full_message":
+			m.Full, _ = v.(string)
+		case "facility":
+			m.Facility, _ = v.(string)
+		case "timestamp":
+			if n, ok := v.(json.Number); ok {
+				m.TimeUnix, _ = n.Float64()
+			}
+		case "level":
+			if n, ok := v.(json.Number); ok {
+				level, _ := n.Int64()
+				m.Level = int32(level)
+			}
+		default:
+			if n, ok := v.(json.Number); ok {
+				if i, err := n.Int64(); err == nil {
+					v = i
+				} else {
+					v, _ = n.Float64()
+				}
+			}
+			m.Extra[strings.TrimPrefix(k, "_")] = v
+		}
+	}
+
+	if m.Short == "" {
+		return nil, errors.New("message has no short_message")
+	}
+
+	return m, nil
+}
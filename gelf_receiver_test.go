@@ -0,0 +1,122 @@
+package gobase
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/Graylog2/go-gelf.v2/gelf"
+)
+
+// waitGelfMessages waits until receiver has n messages
+func waitGelfMessages(t *testing.T, r *GelfReceiver, n int) []*gelf.Message {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		messages := r.Messages()
+		if len(messages) >= n || time.Now().After(deadline) {
+			if len(messages) != n {
+				t.Fatalf("received %d messages, want %d", len(messages), n)
+			}
+			return messages
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func testGelfMessages() []*gelf.Message {
+	return []*gelf.Message{
+		{Version: "1.1", Host: "host1", Short: "db", Full: "query failed", TimeUnix: 1700000000.5, Level: int32(LevelError), Extra: map[string]any{"user_id": 42}},
+		// Random text is not compressed below chunk size
+		{Version: "1.1", Host: "host1", Short: "dump", Full: RandStringBytesMaskImprSrcSB(20000), Level: int32(LevelInfo)},
+	}
+}
+
+func assertGelfMessages(t *testing.T, got, sent []*gelf.Message) {
+	t.Helper()
+
+	for i, m := range sent {
+		g := got[i]
+		if g.Short != m.Short || g.Full != m.Full || g.Host != m.Host || g.Level != m.Level {
+			t.Errorf("message %d: got %s %q level %d from %s", i, g.Short, g.Full[:min(len(g.Full), 20)], g.Level, g.Host)
+		}
+	}
+	if got[0].TimeUnix != 1700000000.5 || got[0].Extra["user_id"] != int64(42) {
+		t.Errorf("timestamp %f, extra %v", got[0].TimeUnix, got[0].Extra)
+	}
+}
+
+func TestGelfReceiverUDP(t *testing.T) {
+	for name, compression := range map[string]gelf.CompressType{"gzip": gelf.CompressGzip, "zlib": gelf.CompressZlib, "none": gelf.CompressNone} {
+		t.Run(name, func(t *testing.T) {
+			r, err := NewGelfReceiver("127.0.0.1:0", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+
+			w, err := gelf.NewUDPWriter(r.Addr())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer w.Close()
+			w.CompressionType = compression
+
+			sent := testGelfMessages()
+			for _, m := range sent {
+				if err := w.WriteMessage(m); err != nil {
+					t.Fatal(err)
+				}
+				// Order of UDP messages is not guaranteed otherwise
+				waitGelfMessages(t, r, len(r.Messages())+1)
+			}
+
+			assertGelfMessages(t, r.Messages(), sent)
+		})
+	}
+}
+
+func TestGelfReceiverTCP(t *testing.T) {
+	r, err := NewGelfReceiver("127.0.0.1:0", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	w, err := gelf.NewTCPWriter(r.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	sent := testGelfMessages()
+	for _, m := range sent {
+		if err := w.WriteMessage(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assertGelfMessages(t, waitGelfMessages(t, r, len(sent)), sent)
+}
+
+func TestGelfReceiverHandler(t *testing.T) {
+	received := make(chan *gelf.Message, 1)
+	r, err := NewGelfReceiver("127.0.0.1:0", func(m *gelf.Message) { received <- m })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	logger := NewGelfLogger("bot", r.Addr(), "host1")
+	logger.Info("startup", "ready", "build", "1.0")
+	defer logger.Close()
+
+	select {
+	case m := <-received:
+		if m.Short != "startup" || m.Full != "ready" || m.Extra["build"] != "1.0" || m.Facility != "bot" {
+			t.Errorf("unexpected message %+v", m)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("message not received")
+	}
+}
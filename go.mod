@@ -6,14 +6,17 @@ require (
 	dario.cat/mergo v1.0.1
 	github.com/go-faster/errors v0.7.1
 	github.com/mitchellh/mapstructure v1.5.0
+	go.opentelemetry.io/proto/otlp v1.4.0
 	google.golang.org/grpc v1.68.1
+	google.golang.org/protobuf v1.35.2
 	gopkg.in/Graylog2/go-gelf.v2 v2.0.0-20191017102106-1550ee647df0
 )
 
 require (
+	github.com/grpc-ecosystem/grpc-gateway/v2 v2.24.0 // indirect
 	golang.org/x/net v0.32.0 // indirect
 	golang.org/x/sys v0.28.0 // indirect
 	golang.org/x/text v0.21.0 // indirect
+	google.golang.org/genproto/googleapis/api v0.0.0-20241118233622-e639e219e697 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20241206012308-a4fef0638583 // indirect
-	google.golang.org/protobuf v1.35.2 // indirect
 )
@@ -0,0 +1,165 @@
+package gobase
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Backoff of restarts of a goroutine started with GoRestart
+type Backoff struct {
+	// Min is delay before the first restart, doubled for every next one, default 1s
+	Min time.Duration
+	// Max is the longest delay, default 1m; a run longer than Max resets delay to Min
+	Max time.Duration
+	// MaxRestarts stops restarting after this many restarts in a row, 0 means no limit
+	MaxRestarts int
+}
+
+// Group tracks goroutines started with Go and GoRestart: it recovers and reports their panics,
+// counts live goroutines by name and waits for them on shutdown:
+//
+//	gobase.Shutdown.Add("goroutines", gobase.Goroutines.Wait)
+type Group struct {
+	mu     sync.Mutex
+	counts map[string]int
+	wg     sync.WaitGroup
+}
+
+func NewGroup() *Group {
+	return &Group{counts: map[string]int{}}
+}
+
+// Goroutines is the group of Go and GoRestart functions
+var Goroutines = NewGroup()
+
+// Go runs fn in goroutine of the Goroutines group, see Group.Go:
+//
+//	gobase.Go(ctx, logger, "queue", func(context.Context) error { return q.RunErr() })
+func Go(ctx context.Context, l Logger, name string, fn func(ctx context.Context) error) {
+	Goroutines.Go(ctx, l, name, fn)
+}
+
+// GoRestart runs fn in goroutine of the Goroutines group, see Group.GoRestart
+func GoRestart(ctx context.Context, l Logger, name string, backoff Backoff, fn func(ctx context.Context) error) {
+	Goroutines.GoRestart(ctx, l, name, backoff, fn)
+}
+
+// Go runs fn in a new goroutine. A panic is recovered and reported (see LogPanicErr) with l,
+// an error returned by fn is logged. If l is nil, logger of ctx is used (see LoggerFrom).
+// fn gets ctx carrying the logger.
+func (g *Group) Go(ctx context.Context, l Logger, name string, fn func(ctx context.Context) error) {
+	g.start(ctx, l, name, func(ctx context.Context, l Logger) {
+		g.run(ctx, l, name, fn)
+	})
+}
+
+// GoRestart is Go restarting fn with backoff after it panics or returns error, until ctx is done.
+// fn returning nil is not restarted.
+func (g *Group) GoRestart(ctx context.Context, l Logger, name string, backoff Backoff, fn func(ctx context.Context) error) {
+	if backoff.Min <= 0 {
+		backoff.Min = time.Second
+	}
+	if backoff.Max <= 0 {
+		backoff.Max = time.Minute
+	}
+
+	g.start(ctx, l, name, func(ctx context.Context, l Logger) {
+		delay := backoff.Min
+
+		for restarts := 0; ; restarts++ {
+			started := time.Now()
+
+			err := g.run(ctx, l, name, fn)
+			if err == nil || ctx.Err() != nil {
+				return
+			}
+
+			if time.Since(started) > backoff.Max {
+				delay, restarts = backoff.Min, 0
+			}
+
+			if backoff.MaxRestarts > 0 && restarts >= backoff.MaxRestarts {
+				l.Error("goroutine", "goroutine "+name+" is not restarted any more", "goroutine", name, "restarts", restarts)
+				return
+			}
+
+			l.Warn("goroutine", "goroutine "+name+" restarts", "goroutine", name, "delay_ms", delay.Milliseconds(), "restarts", restarts+1)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+
+			delay = min(delay*2, backoff.Max)
+		}
+	})
+}
+
+func (g *Group) start(ctx context.Context, l Logger, name string, body func(ctx context.Context, l Logger)) {
+	if l == nil {
+		l = LoggerFrom(ctx)
+	}
+	ctx = WithLogger(ctx, l)
+
+	g.mu.Lock()
+	g.counts[name]++
+	g.mu.Unlock()
+
+	g.wg.Add(1)
+
+	go func() {
+		defer func() {
+			g.mu.Lock()
+			if g.counts[name]--; g.counts[name] == 0 {
+				delete(g.counts, name)
+			}
+			g.mu.Unlock()
+
+			g.wg.Done()
+		}()
+
+		body(ctx, l)
+	}()
+}
+
+// run calls fn once, returning its error or error of recovered panic
+func (g *Group) run(ctx context.Context, l Logger, name string, fn func(ctx context.Context) error) (err error) {
+	defer LogPanicErr(&err, l, "goroutine", name)
+
+	if err = fn(ctx); err != nil && ctx.Err() == nil {
+		l.Error("goroutine", "goroutine "+name+" failed", err, "goroutine", name)
+	}
+
+	return err
+}
+
+// Counts returns number of live goroutines by name
+func (g *Group) Counts() map[string]int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	counts := make(map[string]int, len(g.counts))
+	for name, n := range g.counts {
+		counts[name] = n
+	}
+
+	return counts
+}
+
+// Wait blocks until all goroutines of the group return, or ctx is done (goroutines are stopped by cancelling their ctx)
+func (g *Group) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
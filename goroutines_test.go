@@ -0,0 +1,106 @@
+package gobase
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-faster/errors"
+)
+
+func TestGroupGoRecoversPanic(t *testing.T) {
+	logger := NewRecordingLogger()
+	g := NewGroup()
+
+	g.Go(context.Background(), logger, "worker", func(context.Context) error {
+		panic("worker failed")
+	})
+	g.Go(context.Background(), logger, "fetcher", func(context.Context) error {
+		return errors.New("fetch failed")
+	})
+
+	if err := g.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	logger.AssertLogged(t, LevelCritical, "goroutine", "panic in worker", HasField("stacktrace"))
+	logger.AssertLogged(t, LevelError, "goroutine", "goroutine fetcher failed", FieldEquals("goroutine", "fetcher"))
+}
+
+func TestGroupCountsAndWait(t *testing.T) {
+	g := NewGroup()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	block := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	g.Go(ctx, NewRecordingLogger(), "a", block)
+	g.Go(ctx, NewRecordingLogger(), "a", block)
+	g.Go(ctx, NewRecordingLogger(), "b", block)
+
+	if counts := g.Counts(); len(counts) != 2 || counts["a"] != 2 || counts["b"] != 1 {
+		t.Errorf("unexpected counts %v", counts)
+	}
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer waitCancel()
+	if err := g.Wait(waitCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait returned %v with running goroutines", err)
+	}
+
+	cancel()
+	if err := g.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if counts := g.Counts(); len(counts) != 0 {
+		t.Errorf("counts %v after goroutines returned", counts)
+	}
+}
+
+func TestGroupGoRestartMaxRestarts(t *testing.T) {
+	logger := NewRecordingLogger()
+	g := NewGroup()
+
+	var calls atomic.Int32
+	g.GoRestart(context.Background(), logger, "worker", Backoff{Min: time.Millisecond, Max: 4 * time.Millisecond, MaxRestarts: 3}, func(context.Context) error {
+		calls.Add(1)
+		return errors.New("failed")
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := g.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := calls.Load(); n != 4 {
+		t.Errorf("called %d times, want 4", n)
+	}
+	for i, delay := range []int{1, 2, 4} {
+		logger.AssertLogged(t, LevelWarning, "goroutine", "goroutine worker restarts", FieldEquals("restarts", i+1), FieldEquals("delay_ms", delay))
+	}
+	logger.AssertLogged(t, LevelError, "goroutine", "is not restarted any more", FieldEquals("restarts", 3))
+}
+
+func TestGroupGoRestartStopsOnSuccess(t *testing.T) {
+	g := NewGroup()
+
+	var calls atomic.Int32
+	g.GoRestart(context.Background(), NewRecordingLogger(), "worker", Backoff{Min: time.Millisecond}, func(context.Context) error {
+		if calls.Add(1) == 1 {
+			panic("first run failed")
+		}
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := g.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("called %d times, want 2", n)
+	}
+}
@@ -0,0 +1,264 @@
+package gobase
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// RequestIDMetadataKey is gRPC metadata key carrying request ID between services
+const RequestIDMetadataKey = "x-request-id"
+
+// Longest request ID accepted from client, nested IDs of a deep call chain included
+const maxIncomingRequestIDLength = 128
+
+// NewRequestID generates random request ID
+func NewRequestID() string {
+	return RandStringBytesMaskImprSrcSB(12)
+}
+
+// RequestIDFrom returns "request_uid" field of the logger (see AddRequestID), or empty string
+func RequestIDFrom(l Logger) string {
+	if l == nil {
+		return ""
+	}
+	ruid, _ := l.Fields()["request_uid"].(string)
+	return ruid
+}
+
+// incomingRequestID returns request ID sent by client in metadata, or empty string.
+// An ID of a call chain that is too long is cut to its root segment (the first one, before "/"),
+// so that calls stay correlated. An ID that is still too long or has characters other than
+// letters, digits and "-_./:" is replaced with a new one, it is written to logs and stderr as is.
+func incomingRequestID(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	v := md.Get(RequestIDMetadataKey)
+	if len(v) == 0 || v[0] == "" {
+		return ""
+	}
+
+	id := v[0]
+	if len(id) > maxIncomingRequestIDLength {
+		id, _, _ = strings.Cut(id, "/")
+	}
+
+	if !validRequestID(id) {
+		return NewRequestID()
+	}
+
+	return id
+}
+
+func validRequestID(id string) bool {
+	if len(id) > maxIncomingRequestIDLength {
+		return false
+	}
+
+	for _, c := range []byte(id) {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == '/', c == ':':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// incomingTraceParent returns trace and span IDs of W3C "traceparent" metadata sent by client (OpenTelemetry instrumented)
+func incomingTraceParent(ctx context.Context) (string, string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", "", false
+	}
+
+	v := md.Get("traceparent")
+	if len(v) == 0 {
+		return "", "", false
+	}
+
+	// version-traceid-spanid-flags, e.g. "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	parts := strings.Split(v[0], "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 || !isHex(parts[1]) || !isHex(parts[2]) {
+		return "", "", false
+	}
+
+	return parts[1], parts[2], true
+}
+
+func isHex(s string) bool {
+	for _, c := range []byte(s) {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// newCallLogger makes per-call logger: request ID of the call is nested in ID received from client ("client/call"),
+// base logger is taken from ctx, or l, or the default logger. Returns the logger and full request ID.
+func newCallLogger(ctx context.Context, l Logger, method string) (Logger, string) {
+	base, ok := loggerFromContext(ctx)
+	if !ok {
+		base = l
+	}
+	if base == nil {
+		base = DefaultLogger()
+	}
+
+	ruid := RequestIDFrom(base)
+	if incoming := incomingRequestID(ctx); incoming != "" && incoming != ruid {
+		base = base.AddRequestID(incoming)
+		ruid = joinRequestID(ruid, incoming)
+	}
+
+	fields := map[string]any{"grpc_method": method}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		fields["peer"] = p.Addr.String()
+	}
+	if traceID, spanID, ok := incomingTraceParent(ctx); ok {
+		fields["trace_id"], fields["span_id"] = traceID, spanID
+	}
+
+	callID := NewRequestID()
+
+	return base.AddRequestID(callID, fields), joinRequestID(ruid, callID)
+}
+
+// joinRequestID nests request IDs the same way AddRequestID does
+func joinRequestID(parent, id string) string {
+	if parent == "" {
+		return id
+	}
+	return parent + "/" + id
+}
+
+// grpcCodeLevel chooses level to log finished call with
+func grpcCodeLevel(code codes.Code, okLevel Level) Level {
+	switch code {
+	case codes.OK:
+		return okLevel
+	case codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists, codes.PermissionDenied,
+		codes.Unauthenticated, codes.FailedPrecondition, codes.OutOfRange, codes.ResourceExhausted, codes.Aborted:
+		return LevelWarning
+	default:
+		return LevelError
+	}
+}
+
+func logCallFinished(l Logger, okLevel Level, method string, message string, started time.Time, err error) {
+	code := status.Code(err)
+	level := grpcCodeLevel(code, okLevel)
+
+	if !l.Enabled(level, "grpc") {
+		return
+	}
+
+	fields := map[string]any{
+		"grpc_method": method,
+		"grpc_code":   code.String(),
+		"duration_ms": time.Since(started).Milliseconds(),
+	}
+	for k, v := range ErrorField(err) {
+		fields[k] = v
+	}
+
+	l.Message(level, "grpc", message, fields)
+}
+
+// LoggingUnaryServerInterceptor creates per-call logger with request ID (received in metadata or generated),
+// places it in handler context (see LoggerFrom) and logs method, peer, status code and duration.
+// If l is nil, logger is taken from context or the default logger at call time.
+func LoggingUnaryServerInterceptor(l Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		started := time.Now()
+
+		callLogger, ruid := newCallLogger(ctx, l, info.FullMethod)
+		grpc.SetHeader(ctx, metadata.Pairs(RequestIDMetadataKey, ruid))
+
+		resp, err := handler(WithLogger(ctx, callLogger), req)
+
+		logCallFinished(callLogger, LevelInfo, info.FullMethod, "unary call "+info.FullMethod, started, err)
+
+		return resp, err
+	}
+}
+
+// LoggingStreamServerInterceptor is stream version of LoggingUnaryServerInterceptor
+func LoggingStreamServerInterceptor(l Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		started := time.Now()
+		ctx := ss.Context()
+
+		callLogger, ruid := newCallLogger(ctx, l, info.FullMethod)
+		ss.SetHeader(metadata.Pairs(RequestIDMetadataKey, ruid))
+
+		err := handler(srv, &contextServerStream{ServerStream: ss, ctx: WithLogger(ctx, callLogger)})
+
+		logCallFinished(callLogger, LevelInfo, info.FullMethod, "stream "+info.FullMethod, started, err)
+
+		return err
+	}
+}
+
+// outgoingCallLogger returns logger for client call with request ID, and context sending the ID to server
+func outgoingCallLogger(ctx context.Context, l Logger) (context.Context, Logger) {
+	base, ok := loggerFromContext(ctx)
+	if !ok {
+		base = l
+	}
+	if base == nil {
+		base = DefaultLogger()
+	}
+
+	ruid := RequestIDFrom(base)
+	if ruid == "" {
+		ruid = NewRequestID()
+		base = base.AddRequestID(ruid)
+	}
+
+	return metadata.AppendToOutgoingContext(ctx, RequestIDMetadataKey, ruid), base
+}
+
+// LoggingUnaryClientInterceptor sends request ID of the context logger (see LoggerFrom) to server in metadata,
+// generating one if missing, and logs method, status code and duration.
+// If l is nil, logger is taken from context or the default logger at call time.
+func LoggingUnaryClientInterceptor(l Logger) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		started := time.Now()
+
+		ctx, callLogger := outgoingCallLogger(ctx, l)
+
+		err := invoker(ctx, method, req, reply, cc, opts...)
+
+		logCallFinished(callLogger, LevelDebug, method, "client unary call "+method, started, err)
+
+		return err
+	}
+}
+
+// LoggingStreamClientInterceptor is stream version of LoggingUnaryClientInterceptor, logs when stream is established
+func LoggingStreamClientInterceptor(l Logger) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		started := time.Now()
+
+		ctx, callLogger := outgoingCallLogger(ctx, l)
+
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+
+		logCallFinished(callLogger, LevelDebug, method, "client stream "+method, started, err)
+
+		return stream, err
+	}
+}
@@ -0,0 +1,49 @@
+package gobase
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestIncomingRequestID(t *testing.T) {
+	incoming := func(id string) string {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(RequestIDMetadataKey, id))
+		return incomingRequestID(ctx)
+	}
+
+	for _, id := range []string{"abc", "client/call-1", "svc:a_b.c"} {
+		if got := incoming(id); got != id {
+			t.Errorf("valid request ID %q replaced with %q", id, got)
+		}
+	}
+
+	for _, id := range []string{"a\nFAKE log line", "a b", strings.Repeat("a", maxIncomingRequestIDLength+1)} {
+		got := incoming(id)
+		if got == id || !validRequestID(got) || got == "" {
+			t.Errorf("invalid request ID %q accepted as %q", id, got)
+		}
+	}
+
+	chain := "root" + strings.Repeat("/"+strings.Repeat("x", 12), 10)
+	if got := incoming(chain); got != "root" {
+		t.Errorf("long request ID %q cut to %q, want root segment", chain, got)
+	}
+
+	if got := incomingRequestID(context.Background()); got != "" {
+		t.Errorf("request ID %q without metadata", got)
+	}
+}
+
+func TestNewCallLoggerNestsRequestID(t *testing.T) {
+	logger := NewRecordingLogger()
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(RequestIDMetadataKey, "client"))
+	callLogger, ruid := newCallLogger(ctx, logger, "/svc/Method")
+
+	if !strings.HasPrefix(ruid, "client/") || RequestIDFrom(callLogger) != ruid {
+		t.Errorf("unexpected request ID %q, logger has %q", ruid, RequestIDFrom(callLogger))
+	}
+}
@@ -0,0 +1,68 @@
+package gobase
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// recoveryLogger returns logger of the call and correlation ID reported to client (request ID of the logger, generated if missing)
+func recoveryLogger(ctx context.Context, l Logger) (Logger, string) {
+	base, ok := loggerFromContext(ctx)
+	if !ok {
+		base = l
+	}
+	if base == nil {
+		base = DefaultLogger()
+	}
+
+	ruid := RequestIDFrom(base)
+	if ruid == "" {
+		ruid = NewRequestID()
+		base = base.AddRequestID(ruid)
+	}
+
+	return base, ruid
+}
+
+func panicStatusError(correlationID string) error {
+	return status.Errorf(codes.Internal, "internal error, correlation id: %s", correlationID)
+}
+
+// RecoveryUnaryServerInterceptor recovers from handler panic, logs it with stacktrace (see LogPanicErr)
+// and returns codes.Internal error with correlation ID (request ID in logs) to client.
+// If l is nil, logger is taken from context or the default logger at call time.
+func RecoveryUnaryServerInterceptor(l Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		callLogger, correlationID := recoveryLogger(ctx, l)
+
+		var panicErr error
+		defer func() {
+			if panicErr != nil {
+				resp, err = nil, panicStatusError(correlationID)
+			}
+		}()
+		defer LogPanicErr(&panicErr, callLogger, "grpc", info.FullMethod)
+
+		return handler(ctx, req)
+	}
+}
+
+// RecoveryStreamServerInterceptor is stream version of RecoveryUnaryServerInterceptor
+func RecoveryStreamServerInterceptor(l Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		callLogger, correlationID := recoveryLogger(ss.Context(), l)
+
+		var panicErr error
+		defer func() {
+			if panicErr != nil {
+				err = panicStatusError(correlationID)
+			}
+		}()
+		defer LogPanicErr(&panicErr, callLogger, "grpc", info.FullMethod)
+
+		return handler(srv, ss)
+	}
+}
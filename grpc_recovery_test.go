@@ -0,0 +1,110 @@
+package gobase
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// panickingHealthServer panics for service "panic"
+type panickingHealthServer struct {
+	healthpb.UnimplementedHealthServer
+}
+
+func (panickingHealthServer) Check(_ context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	if req.GetService() == "panic" {
+		panic("handler failed")
+	}
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
+func (panickingHealthServer) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	if req.GetService() == "panic" {
+		panic("stream handler failed")
+	}
+	return stream.Send(&healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING})
+}
+
+func startRecoveryTestServer(t *testing.T, logger Logger) healthpb.HealthClient {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(RecoveryUnaryServerInterceptor(logger)),
+		grpc.ChainStreamInterceptor(RecoveryStreamServerInterceptor(logger)),
+	)
+	healthpb.RegisterHealthServer(server, panickingHealthServer{})
+	go server.Serve(l)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(l.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return healthpb.NewHealthClient(conn)
+}
+
+// assertPanicStatus checks that err is codes.Internal with correlation ID of logged panic with stacktrace
+func assertPanicStatus(t *testing.T, logger *RecordingLogger, err error, method string) {
+	t.Helper()
+
+	st := status.Convert(err)
+	if st.Code() != codes.Internal {
+		t.Fatalf("unexpected status %v", err)
+	}
+
+	messages := logger.Find(LevelCritical, "grpc", "panic in "+method, HasField("stacktrace"))
+	if len(messages) != 1 {
+		t.Fatalf("panic not logged:\n%s", logger.dump())
+	}
+	if ruid := messages[0].RequestID; ruid == "" || !strings.HasSuffix(st.Message(), "correlation id: "+ruid) {
+		t.Errorf("status %q has no correlation ID %q", st.Message(), ruid)
+	}
+	if stack, _ := messages[0].Fields["stacktrace"].(string); !strings.Contains(stack, "panickingHealthServer") {
+		t.Errorf("stacktrace has no handler: %s", stack)
+	}
+}
+
+func TestRecoveryUnaryServerInterceptor(t *testing.T) {
+	logger := NewRecordingLogger()
+	client := startRecoveryTestServer(t, logger)
+
+	_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "panic"})
+	assertPanicStatus(t, logger, err, "/grpc.health.v1.Health/Check")
+
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Errorf("server stopped serving after panic: %v", err)
+	}
+}
+
+func TestRecoveryStreamServerInterceptor(t *testing.T) {
+	logger := NewRecordingLogger()
+	client := startRecoveryTestServer(t, logger)
+
+	stream, err := client.Watch(context.Background(), &healthpb.HealthCheckRequest{Service: "panic"})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	assertPanicStatus(t, logger, err, "/grpc.health.v1.Health/Watch")
+
+	stream, err = client.Watch(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Errorf("server stopped serving after panic: %v", err)
+	}
+}
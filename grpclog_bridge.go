@@ -0,0 +1,102 @@
+package gobase
+
+import (
+	"fmt"
+	"regexp"
+
+	"google.golang.org/grpc/grpclog"
+)
+
+// "[core]" component prefix of grpc-go messages
+var grpclogComponentPrefix = regexp.MustCompile(`^\[([\w-]+)\]\s*`)
+
+// GRPCLogger is grpclog.LoggerV2 writing grpc-go internal messages to Logger,
+// with kind "grpclog", "source" field "grpc-go" and "component" field (core, transport, ...) if present.
+// Info messages are logged at LevelDebug, grpc-go info output is too verbose for production logs.
+type GRPCLogger struct {
+	logger    Logger
+	verbosity int
+}
+
+// NewGRPCLogger makes grpclog.LoggerV2, verbosity is same as GRPC_GO_LOG_VERBOSITY_LEVEL
+func NewGRPCLogger(logger Logger, verbosity int) *GRPCLogger {
+	return &GRPCLogger{
+		logger:    logger,
+		verbosity: verbosity,
+	}
+}
+
+// CaptureGRPCLogs sets logger as grpc-go internal logger, should be called before any gRPC functions
+func CaptureGRPCLogs(logger Logger, verbosity int) {
+	grpclog.SetLoggerV2(NewGRPCLogger(logger, verbosity))
+}
+
+func (g *GRPCLogger) log(level Level, message string) {
+	if !g.logger.Enabled(level, "grpclog") {
+		return
+	}
+
+	fields := map[string]any{"source": "grpc-go"}
+	if m := grpclogComponentPrefix.FindStringSubmatch(message); m != nil {
+		fields["component"] = m[1]
+		message = message[len(m[0]):]
+	}
+
+	g.logger.Message(level, "grpclog", message, fields)
+}
+
+func (g *GRPCLogger) Info(args ...any) {
+	g.log(LevelDebug, fmt.Sprint(args...))
+}
+
+func (g *GRPCLogger) Infoln(args ...any) {
+	g.log(LevelDebug, fmt.Sprint(args...))
+}
+
+func (g *GRPCLogger) Infof(format string, args ...any) {
+	g.log(LevelDebug, fmt.Sprintf(format, args...))
+}
+
+func (g *GRPCLogger) Warning(args ...any) {
+	g.log(LevelWarning, fmt.Sprint(args...))
+}
+
+func (g *GRPCLogger) Warningln(args ...any) {
+	g.log(LevelWarning, fmt.Sprint(args...))
+}
+
+func (g *GRPCLogger) Warningf(format string, args ...any) {
+	g.log(LevelWarning, fmt.Sprintf(format, args...))
+}
+
+func (g *GRPCLogger) Error(args ...any) {
+	g.log(LevelError, fmt.Sprint(args...))
+}
+
+func (g *GRPCLogger) Errorln(args ...any) {
+	g.log(LevelError, fmt.Sprint(args...))
+}
+
+func (g *GRPCLogger) Errorf(format string, args ...any) {
+	g.log(LevelError, fmt.Sprintf(format, args...))
+}
+
+// Fatal logs at critical level and exits with Exit, as grpclog.LoggerV2 requires
+func (g *GRPCLogger) Fatal(args ...any) {
+	g.log(LevelCritical, fmt.Sprint(args...))
+	Exit(1)
+}
+
+func (g *GRPCLogger) Fatalln(args ...any) {
+	g.log(LevelCritical, fmt.Sprint(args...))
+	Exit(1)
+}
+
+func (g *GRPCLogger) Fatalf(format string, args ...any) {
+	g.log(LevelCritical, fmt.Sprintf(format, args...))
+	Exit(1)
+}
+
+func (g *GRPCLogger) V(l int) bool {
+	return l <= g.verbosity
+}
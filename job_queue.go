@@ -2,10 +2,17 @@ package gobase
 
 import (
 	"context"
-	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"gopkg.in/Graylog2/go-gelf.v2/gelf"
+	"github.com/go-faster/errors"
+)
+
+// Jobs waiting in queue or running longer are logged at LevelWarning, see JobQueue.SetSlowThresholds
+const (
+	DefaultJobSlowWait = time.Second
+	DefaultJobSlowExec = time.Second
 )
 
 // Context passed to the operation func will tell it is cancelled if queue is stopping
@@ -18,32 +25,134 @@ type JobOp func(context.Context)
 type JobQueue struct {
 	name   string
 	logger Logger
+	op     chan JobOp
+
+	mu     sync.Mutex // guards ctx and cancel, read by Run and IsReady while Stop clears them
 	ctx    context.Context
 	cancel context.CancelFunc
-	op     chan JobOp
+
+	slowWait, slowExec time.Duration
+
+	jobStarted atomic.Int64 // unix nanoseconds, 0 when no job is running
+	jobsDone   atomic.Uint64
+}
+
+// JobQueueState is a snapshot of queue for debug dumps
+type JobQueueState struct {
+	Name       string
+	Pending    int           // jobs waiting in queue
+	Backlog    int           // queue capacity
+	Running    bool          // a job is running
+	RunningFor time.Duration // time the running job takes so far
+	Done       uint64        // jobs finished
+}
+
+// jobQueues are queues with Run in progress, for debug dumps
+var jobQueues sync.Map
+
+// JobQueueStates returns states of queues with Run in progress
+func JobQueueStates() []JobQueueState {
+	var states []JobQueueState
+
+	jobQueues.Range(func(key, _ any) bool {
+		states = append(states, key.(*JobQueue).State())
+		return true
+	})
+
+	return states
+}
+
+// State returns snapshot of the queue, safe to call from any goroutine
+func (q *JobQueue) State() JobQueueState {
+	state := JobQueueState{
+		Name:    q.name,
+		Pending: len(q.op),
+		Backlog: cap(q.op),
+		Done:    q.jobsDone.Load(),
+	}
+
+	if started := q.jobStarted.Load(); started != 0 {
+		state.Running = true
+		state.RunningFor = time.Since(time.Unix(0, started))
+	}
+
+	return state
 }
 
 // Makes new Queue (unintialized)
 // Without Initialize, Enqueue takes up to to [backlog] operations before blocked.
 // [backlog] defines number of operations pre-scheduled (pending) in queue, a non-zero value will lead to losing some if queue is Stopped
 func NewJobQueue(name string, logger Logger, backlog int) *JobQueue {
-	return &JobQueue{
+	q := &JobQueue{
 		name:   name,
 		logger: logger,
 		op:     make(chan JobOp, backlog),
+
+		slowWait: DefaultJobSlowWait,
+		slowExec: DefaultJobSlowExec,
+	}
+
+	return q
+}
+
+// SetSlowThresholds sets how long a job can wait in queue and run before it is logged as slow,
+// with "wait_ms" and "duration_ms" fields. With zero thresholds every job is logged at LevelDebug.
+// Must be called before jobs are queued.
+func (q *JobQueue) SetSlowThresholds(wait, exec time.Duration) {
+	q.slowWait, q.slowExec = wait, exec
+}
+
+// timedJob runs op with logger of caller context (if any), logging its wait and exec time
+func (q *JobQueue) timedJob(callerCtx context.Context, enqueued time.Time, op JobOp) JobOp {
+	return func(qctx context.Context) {
+		qctx = withCallerLogger(qctx, callerCtx)
+
+		wait := time.Since(enqueued)
+		timing := LoggerFrom(qctx).Start("queue", q.name+" Queue::job", "queue", q.name, "wait_ms", wait.Milliseconds()).
+			Level(LevelDebug).
+			Slow(q.slowExec)
+
+		if q.slowWait > 0 && wait >= q.slowWait {
+			timing.Slow(0).Level(LevelWarning)
+			timing.SetField("slow_wait", true)
+		}
+
+		finished := false
+		defer func() {
+			// The panic itself is reported by Run
+			if !finished {
+				timing.End(errors.New("job panicked"))
+			}
+		}()
+
+		op(qctx)
+		finished = true
+
+		timing.End(nil)
 	}
 }
 
 // Create queue context (cancellable) for Run() goroutine
 // Initializing queue must be followed by spawning Run() goroutine.
+// Queue logger is placed in the context (see LoggerFrom), unless ctx already carries one.
 func (q *JobQueue) Initialize(ctx context.Context) {
-	q.logger.Message(gelf.LOG_DEBUG, "queue", fmt.Sprintf("%s Queue::Initialize", q.name))
+	q.logger.Debugf("queue", "%s Queue::Initialize", q.name)
+
+	if _, ok := loggerFromContext(ctx); !ok {
+		ctx = WithLogger(ctx, q.logger)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
 
 	q.ctx, q.cancel = context.WithCancel(ctx)
 }
 
 // IsReady tests if queue is intiailized and was not stopped
 func (q *JobQueue) IsReady() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
 	return q.ctx != nil && q.cancel != nil && q.op != nil
 }
 
@@ -53,7 +162,11 @@ func (q *JobQueue) IsReady() bool {
 // Context passed to the operation func will tell it is cancelled if queue is stopping
 // TODO: block before Run() is exited?
 func (q *JobQueue) Stop() {
-	q.logger.Message(gelf.LOG_DEBUG, "queue", fmt.Sprintf("%s Queue::Stop", q.name))
+	q.logger.Debugf("queue", "%s Queue::Stop", q.name)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
 	q.cancel()
 	close(q.op)
 	q.ctx = nil
@@ -61,24 +174,49 @@ func (q *JobQueue) Stop() {
 }
 
 // Goroutine that performs all future operations in order.
+// A panicking job is logged (see LogPanic) and ends Run, use RunErr to restart the queue.
 func (q *JobQueue) Run() {
-	q.logger.Message(gelf.LOG_DEBUG, "queue", fmt.Sprintf("%s Queue::Run", q.name))
+	q.RunErr()
+}
+
+// RunErr is Run returning error if it was ended by a panicking job, nil if the queue was stopped.
+// GoRestart restarts the queue after panics with it:
+//
+//	gobase.GoRestart(ctx, logger, "queue", gobase.Backoff{}, func(context.Context) error { return q.RunErr() })
+func (q *JobQueue) RunErr() (err error) {
+	q.mu.Lock()
+	ctx := q.ctx
+	q.mu.Unlock()
+
+	if ctx == nil {
+		q.logger.Errorf("queue", "%s Queue::Run before Initialize", q.name)
+		return errors.New("queue " + q.name + " is not initialized")
+	}
+
+	q.logger.Debugf("queue", "%s Queue::Run", q.name)
 
-	defer q.logger.Message(gelf.LOG_WARNING, "queue", fmt.Sprintf("%s Queue::Run end", q.name))
+	jobQueues.Store(q, struct{}{})
+	defer jobQueues.Delete(q)
+
+	defer q.logger.Warnf("queue", "%s Queue::Run end", q.name)
+
+	// Install panic handler with logging on this thread/goroutine
+	defer LogPanicErr(&err, q.logger, "queue", q.name+" job")
+	defer q.jobStarted.Store(0)
 
 	for {
 		select {
 		case op := <-q.op:
 			if op == nil { // normally channel termination
-				return
+				return nil
 			}
 
-			// Install panic handler with logging on this thread/goroutine
-			defer LogPanic(q.logger, "queue")
-
-			op(q.ctx)
-		case <-q.ctx.Done():
-			return
+			q.jobStarted.Store(time.Now().UnixNano())
+			op(ctx)
+			q.jobStarted.Store(0)
+			q.jobsDone.Add(1)
+		case <-ctx.Done():
+			return nil
 		}
 	}
 }
@@ -86,12 +224,28 @@ func (q *JobQueue) Run() {
 // Push operation to be executed after others queued before.
 // May block if queue blocking (is full)
 func (q *JobQueue) Enqueue(op JobOp) {
-	q.op <- op
+	q.op <- q.timedJob(context.Background(), time.Now(), op)
+}
+
+// Push operation to be executed after others queued before.
+// Operation context carries logger of the given ctx (see LoggerFrom), if any.
+// May block if queue blocking (is full)
+func (q *JobQueue) EnqueueContext(ctx context.Context, op JobOp) {
+	q.op <- q.timedJob(ctx, time.Now(), op)
+}
+
+// withCallerLogger returns queue context carrying logger from caller context, if any
+func withCallerLogger(qctx context.Context, callerCtx context.Context) context.Context {
+	if l, ok := loggerFromContext(callerCtx); ok {
+		return WithLogger(qctx, l)
+	}
+	return qctx
 }
 
 // Push operation to be executed after others queued before.
 // This method will block until the operation finishes.
 // Operation won't run if given context is cancelled
+// Operation context carries logger of the given ctx (see LoggerFrom), if any.
 // Return value is true when the operation was finished and returned.
 func (q *JobQueue) Join(ctx context.Context, op JobOp) bool {
 	c := make(chan bool)
@@ -99,8 +253,10 @@ func (q *JobQueue) Join(ctx context.Context, op JobOp) bool {
 
 	// TODO: add select for context cancellation
 
-	q.op <- func(ctx context.Context) {
-		op(ctx)
+	job := q.timedJob(ctx, time.Now(), op)
+
+	q.op <- func(qctx context.Context) {
+		job(qctx)
 		c <- true
 	}
 
@@ -111,6 +267,7 @@ func (q *JobQueue) Join(ctx context.Context, op JobOp) bool {
 // This method will block until the operation finishes.
 // Operation won't run if given context is cancelled
 // Operation won't run if waiting for queue is longer than the startTimeout
+// Operation context carries logger of the given ctx (see LoggerFrom), if any.
 // Return value is true when the operation was finished and returned.
 func (q *JobQueue) JoinTimeout(ctx context.Context, startTimeout time.Duration, op JobOp) bool {
 	c := make(chan bool)
@@ -120,11 +277,13 @@ func (q *JobQueue) JoinTimeout(ctx context.Context, startTimeout time.Duration,
 
 	// TODO: add select for context cancellation, and Ticker for timeout
 
-	q.op <- func(ctx context.Context) {
+	job := q.timedJob(ctx, started, op)
+
+	q.op <- func(qctx context.Context) {
 		if time.Since(started) >= startTimeout {
 			c <- false
 		} else {
-			op(ctx)
+			job(qctx)
 			c <- true
 		}
 	}
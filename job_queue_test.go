@@ -0,0 +1,113 @@
+package gobase
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func startTestQueue(t *testing.T, logger Logger) (*JobQueue, chan struct{}) {
+	t.Helper()
+
+	q := NewJobQueue("test", logger, 10)
+	q.Initialize(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		q.Run()
+	}()
+
+	return q, done
+}
+
+func waitQueueDone(t *testing.T, done chan struct{}) {
+	t.Helper()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("queue Run did not return")
+	}
+}
+
+func TestJobQueueJoinUsesCallerLogger(t *testing.T) {
+	logger := NewRecordingLogger()
+	q, done := startTestQueue(t, logger)
+
+	ctx := WithLogger(context.Background(), logger.AddRequestID("req1"))
+	ok := q.Join(ctx, func(ctx context.Context) {
+		LoggerFrom(ctx).Info("handler", "in queue")
+	})
+	if !ok {
+		t.Fatal("Join returned false")
+	}
+
+	q.Stop()
+	waitQueueDone(t, done)
+
+	logger.AssertLogged(t, LevelInfo, "handler", "in queue", FieldEquals("request_uid", "req1"))
+	logger.NoErrorsLogged(t)
+}
+
+func TestJobQueueSlowJob(t *testing.T) {
+	logger := NewRecordingLogger()
+	q, done := startTestQueue(t, logger)
+	q.SetSlowThresholds(time.Hour, 10*time.Millisecond)
+
+	q.Join(context.Background(), func(context.Context) { time.Sleep(20 * time.Millisecond) })
+	q.Join(context.Background(), func(context.Context) {})
+
+	q.Stop()
+	waitQueueDone(t, done)
+
+	if n := len(logger.Find(LevelWarning, "queue", "Queue::job", FieldEquals("slow", true))); n != 1 {
+		t.Errorf("%d slow jobs logged, want 1", n)
+	}
+	logger.AssertLogged(t, LevelWarning, "queue", "Queue::job", HasField("duration_ms"), FieldEquals("outcome", "ok"))
+}
+
+func TestJobQueuePanicIsLogged(t *testing.T) {
+	logger := NewRecordingLogger()
+	q, done := startTestQueue(t, logger)
+
+	q.Enqueue(func(context.Context) { panic("job failed") })
+	waitQueueDone(t, done)
+
+	logger.AssertLogged(t, LevelCritical, "queue", "job failed", FieldEquals("panic_value", "job failed"), HasField("stacktrace"))
+	logger.AssertLogged(t, LevelError, "queue", "Queue::job", FieldEquals("outcome", "error"), HasField("duration_ms"))
+}
+
+func TestJobQueueRestartsAfterPanic(t *testing.T) {
+	logger := NewRecordingLogger()
+	q := NewJobQueue("test", logger, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	q.Initialize(ctx)
+
+	g := NewGroup()
+	g.GoRestart(ctx, logger, "queue", Backoff{Min: time.Millisecond}, func(context.Context) error { return q.RunErr() })
+
+	q.Enqueue(func(context.Context) { panic("job failed") })
+	if !q.Join(context.Background(), func(context.Context) {}) {
+		t.Error("queue is not restarted after panic")
+	}
+
+	cancel()
+	q.Stop()
+	if err := g.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	logger.AssertLogged(t, LevelWarning, "goroutine", "goroutine queue restarts")
+}
+
+func TestJobQueueRunErrNotInitialized(t *testing.T) {
+	logger := NewRecordingLogger()
+	q := NewJobQueue("test", logger, 10)
+
+	if err := q.RunErr(); err == nil {
+		t.Error("RunErr of not initialized queue returned nil")
+	}
+	logger.AssertLogged(t, LevelError, "queue", "before Initialize")
+}
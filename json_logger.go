@@ -0,0 +1,49 @@
+package gobase
+
+import (
+	"bytes"
+	"io"
+	"sync"
+
+	"gopkg.in/Graylog2/go-gelf.v2/gelf"
+)
+
+// JSONWriter writes every GELF message as a single JSON object per line (JSON-lines).
+// Field names are the same as the ones sent to Graylog by GelfLogger.
+type JSONWriter struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+func NewJSONWriter(out io.Writer) *JSONWriter {
+	return &JSONWriter{out: out}
+}
+
+func (w *JSONWriter) WriteMessage(m *gelf.Message) error {
+	_, err := w.writeMessage(m)
+	return err
+}
+
+func (w *JSONWriter) writeMessage(m *gelf.Message) (int, error) {
+	var buf bytes.Buffer
+
+	if err := m.MarshalJSONBuf(&buf); err != nil {
+		return 0, err
+	}
+
+	buf.WriteByte('\n')
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.out.Write(buf.Bytes())
+}
+
+func (w *JSONWriter) Close() error {
+	return nil
+}
+
+// NewJSONLogger makes logger writing JSON-lines to out (usually os.Stdout, scraped by container platform)
+func NewJSONLogger(facility, selfHostname string, out io.Writer) Logger {
+	return newGelfLogger(NewJSONWriter(out), facility, selfHostname)
+}
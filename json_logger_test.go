@@ -0,0 +1,48 @@
+package gobase
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJSONWriter(t *testing.T) {
+	var out bytes.Buffer
+	logger := NewJSONLogger("bot", "host1", &out).AddRequestID("req1")
+	logger.Error("db", "query failed", "user_id", 42)
+	logger.Info("db", "multi\nline")
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("%d lines, want one per message:\n%s", len(lines), out.String())
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &fields); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]any{
+		"version":       "1.1",
+		"host":          "host1",
+		"short_message": "db",
+		"full_message":  "query failed",
+		"level":         float64(LevelError),
+		"facility":      "bot",
+		"user_id":       float64(42),
+		"request_uid":   "req1",
+	}
+	for k, v := range want {
+		if fields[k] != v {
+			t.Errorf("%s = %#v, want %#v", k, fields[k], v)
+		}
+	}
+	if _, ok := fields["timestamp"].(float64); !ok {
+		t.Errorf("no timestamp in %s", lines[0])
+	}
+
+	if err := json.Unmarshal([]byte(lines[1]), &fields); err != nil || fields["full_message"] != "multi\nline" {
+		t.Errorf("multi-line message %q: %v", lines[1], err)
+	}
+}
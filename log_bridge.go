@@ -0,0 +1,223 @@
+package gobase
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// stderrLog reports logger failures. It is not the standard logger, which may be redirected to the failing logger itself.
+var stderrLog = log.New(os.Stderr, "", log.LstdFlags)
+
+const levelNamesPattern = `(trace|debug|info|notice|warn|warning|err|error|crit|critical|fatal|panic|alert|emerg|emergency)`
+
+var (
+	// "2024/01/02 15:04:05.000000 " prefix of standard library log (log.LstdFlags, log.Lmicroseconds)
+	stdlogTimePrefix = regexp.MustCompile(`^(\d{4}/\d{2}/\d{2} )?\d{2}:\d{2}:\d{2}(\.\d+)? `)
+	// "file.go:12: " prefix of standard library log (log.Lshortfile, log.Llongfile)
+	stdlogFilePrefix = regexp.MustCompile(`^([^\s:]+\.go):(\d+): `)
+	// "[warn] ", "<error> ", "error: ", "ERROR " level prefixes (a bare level word only in upper case, "Error loading" is a sentence)
+	levelPrefix = regexp.MustCompile(`^(?:[\[<(](?i:` + levelNamesPattern + `)[\]>)]:?|(?i:` + levelNamesPattern + `):|` + strings.ToUpper(levelNamesPattern) + `\b)\s*`)
+	// level=error (logfmt) at line start, or after time= field (logrus)
+	levelField = regexp.MustCompile(`^(?:(?:time|ts)=(?:"[^"]*"|\S+)\s+)?(?i:level|lvl|severity)="?(\w+)"?(?:\s+|$)`)
+	// "level":"error" field of JSON line
+	levelJSONField = regexp.MustCompile(`^\{.*?"(?i:level|lvl|severity)"\s*:\s*"(\w+)"`)
+)
+
+// ParseLogLine detects level of a line written by standard library log or a third-party library,
+// from prefixes like "ERROR", "[WARN]", "error:", or "level=error" / "level":"error" fields.
+// Returned message has standard library log timestamp and file prefixes, level prefix and level= field removed
+// (JSON lines are kept as they are).
+func ParseLogLine(line string) (Level, string, bool) {
+	line = stdlogTimePrefix.ReplaceAllString(line, "")
+	line = stdlogFilePrefix.ReplaceAllString(line, "")
+
+	if m := levelPrefix.FindStringSubmatch(line); m != nil {
+		name := strings.Trim(m[0], "[]<>(): \t")
+		if level, ok := bridgeLevel(name); ok {
+			return level, line[len(m[0]):], true
+		}
+	}
+
+	if m := levelField.FindStringSubmatch(line); m != nil {
+		if level, ok := bridgeLevel(m[1]); ok {
+			return level, line[len(m[0]):], true
+		}
+	}
+
+	if m := levelJSONField.FindStringSubmatch(line); m != nil {
+		if level, ok := bridgeLevel(m[1]); ok {
+			return level, line, true
+		}
+	}
+
+	return 0, line, false
+}
+
+func bridgeLevel(name string) (Level, bool) {
+	switch strings.ToLower(name) {
+	case "trace":
+		return LevelDebug, true
+	case "fatal", "panic":
+		return LevelCritical, true
+	}
+
+	level, err := ParseLevel(name)
+	if err != nil {
+		return 0, false
+	}
+
+	return level, true
+}
+
+// Unterminated line written to LogBridge is logged when it grows to this size
+const logBridgeMaxLine = 64 * 1024
+
+// LogBridge is io.Writer turning output of standard library log or third-party libraries into logger messages:
+// each line becomes a message (indented lines, like stack traces, are appended to the previous one),
+// level is detected with ParseLogLine, defaultLevel is used when there is none.
+// Messages have kind "stdout" and "source" field set to source, if not empty.
+// Lines for DummyLogger, which prints with standard log, are written to stderr as they are:
+// logging them would re-enter standard log (and the bridge, if it is standard log output) and deadlock.
+type LogBridge struct {
+	logger       Logger
+	source       string
+	defaultLevel Level
+
+	mu      sync.Mutex
+	partial []byte
+}
+
+func NewLogBridge(logger Logger, source string, defaultLevel Level) *LogBridge {
+	return &LogBridge{
+		logger:       logger,
+		source:       source,
+		defaultLevel: defaultLevel,
+	}
+}
+
+// Write logs complete lines of p, incomplete last line is kept until next Write or Flush
+func (b *LogBridge) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if printsToStdLog(b.logger) {
+		return os.Stderr.Write(p)
+	}
+
+	data := append(b.partial, p...)
+
+	if end := bytes.LastIndexByte(data, '\n'); end >= 0 {
+		writeLogLines(b.logger, b.source, b.defaultLevel, data[:end])
+		data = data[end+1:]
+	}
+
+	// A writer never ending its line would grow the buffer without limit
+	if len(data) >= logBridgeMaxLine {
+		writeLogLines(b.logger, b.source, b.defaultLevel, data)
+		data = nil
+	}
+
+	b.partial = append([]byte(nil), data...)
+
+	return len(p), nil
+}
+
+// Flush logs incomplete last line
+func (b *LogBridge) Flush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	writeLogLines(b.logger, b.source, b.defaultLevel, b.partial)
+	b.partial = nil
+}
+
+// RedirectStdLog sends standard library log output to logger, returned function restores previous output and flags.
+// Output is not redirected to DummyLogger, which prints with standard log itself.
+func RedirectStdLog(logger Logger) (restore func()) {
+	if printsToStdLog(logger) {
+		return func() {}
+	}
+
+	prevOutput, prevFlags := log.Writer(), log.Flags()
+
+	// Messages have own timestamp, caller is kept as "file" and "line" fields
+	log.SetFlags(log.Lshortfile)
+	log.SetOutput(NewLogBridge(logger, "log", LevelInfo))
+
+	return func() {
+		log.SetOutput(prevOutput)
+		log.SetFlags(prevFlags)
+	}
+}
+
+// printsToStdLog tells if logger writes its messages with standard log
+func printsToStdLog(l Logger) bool {
+	switch l.(type) {
+	case DummyLogger, *DummyLogger:
+		return true
+	}
+	return false
+}
+
+// writeLogLines logs each line of p as a message.
+// Caller fields are set only from "file.go:12: " prefix (see addCallerFields).
+func writeLogLines(l Logger, source string, defaultLevel Level, p []byte) bool {
+	ok := true
+
+	// Lines of one log.Print call share caller printed before the first one
+	var file string
+	var line int
+
+	for _, entry := range splitLogLines(string(p)) {
+		fields := map[string]any{}
+		if source != "" {
+			fields["source"] = source
+		}
+
+		entry = stdlogTimePrefix.ReplaceAllString(entry, "")
+		if m := stdlogFilePrefix.FindStringSubmatch(entry); m != nil {
+			file, entry = m[1], entry[len(m[0]):]
+			line, _ = strconv.Atoi(m[2])
+		}
+		if file != "" {
+			fields["file"], fields["line"] = file, line
+		}
+
+		level, message, found := ParseLogLine(entry)
+		if !found {
+			level = defaultLevel
+		}
+
+		if !l.Message(level, "stdout", message, fields) {
+			ok = false
+		}
+	}
+
+	return ok
+}
+
+// splitLogLines splits text to lines, indented lines are joined with the previous one
+func splitLogLines(s string) []string {
+	var entries []string
+
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimRight(line, "\r ")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if len(entries) > 0 && (line[0] == ' ' || line[0] == '\t') {
+			entries[len(entries)-1] += "\n" + line
+			continue
+		}
+
+		entries = append(entries, line)
+	}
+
+	return entries
+}
@@ -0,0 +1,98 @@
+package gobase
+
+import (
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLogLine(t *testing.T) {
+	tests := []struct {
+		line    string
+		level   Level
+		message string
+	}{
+		{"[ERROR] disk full", LevelError, "disk full"},
+		{"2024/01/02 15:04:05 main.go:12: [ERROR] y", LevelError, "y"},
+		{"warn: retrying", LevelWarning, "retrying"},
+		{"level=error msg=x", LevelError, "msg=x"},
+		{`time="2024-01-02T15:04:05Z" level=warning msg="slow query"`, LevelWarning, `msg="slow query"`},
+		{`{"level":"error","msg":"x"}`, LevelError, `{"level":"error","msg":"x"}`},
+	}
+
+	for _, test := range tests {
+		level, message, ok := ParseLogLine(test.line)
+		if !ok || level != test.level || message != test.message {
+			t.Errorf("ParseLogLine(%q) = %v, %q, %v", test.line, level, message, ok)
+		}
+	}
+
+	for _, line := range []string{"Error loading config", "user set level=debug for db"} {
+		if level, _, ok := ParseLogLine(line); ok {
+			t.Errorf("ParseLogLine(%q) detected level %v", line, level)
+		}
+	}
+}
+
+func TestLogBridgeCallerFields(t *testing.T) {
+	logger := NewRecordingLogger()
+	b := NewLogBridge(logger, "lib", LevelInfo)
+
+	b.Write([]byte("client.go:42: connected\n"))
+	b.Write([]byte("no caller\n"))
+
+	logger.AssertLogged(t, LevelInfo, "stdout", "connected", FieldEquals("file", "client.go"), FieldEquals("line", 42))
+
+	messages := logger.Find(LevelInfo, "stdout", "no caller")
+	if len(messages) != 1 {
+		t.Fatalf("line not logged:\n%s", logger.dump())
+	}
+	if file, ok := messages[0].Fields["file"]; ok {
+		t.Errorf("line without file prefix has file %v", file)
+	}
+}
+
+func TestRedirectStdLogToDummyLogger(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		restore := RedirectStdLog(DummyLogger{})
+		log.Println("through redirected log")
+		restore()
+
+		log.SetOutput(NewLogBridge(DummyLogger{}, "log", LevelInfo))
+		log.Println("through bridge")
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("logging with DummyLogger deadlocked")
+	}
+}
+
+func TestLogBridgeLongLineFlushed(t *testing.T) {
+	logger := NewRecordingLogger()
+	b := NewLogBridge(logger, "lib", LevelInfo)
+
+	chunk := []byte(strings.Repeat("x", 1024))
+	for i := 0; i < logBridgeMaxLine/len(chunk)+10; i++ {
+		b.Write(chunk)
+	}
+
+	if n := len(logger.Messages()); n != 1 {
+		t.Fatalf("%d messages logged for unterminated line, want 1", n)
+	}
+	if len(b.partial) >= logBridgeMaxLine {
+		t.Errorf("buffer keeps %d bytes", len(b.partial))
+	}
+
+	b.Write([]byte("end\n"))
+	if n := len(logger.Messages()); n != 2 {
+		t.Errorf("%d messages logged, want 2", n)
+	}
+}
@@ -0,0 +1,62 @@
+package gobase
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/go-faster/errors"
+	"gopkg.in/Graylog2/go-gelf.v2/gelf"
+)
+
+// Level is syslog severity of a message, same values as gelf.LOG_* constants.
+// Lower value is more severe.
+type Level int32
+
+const (
+	LevelEmergency Level = gelf.LOG_EMERG
+	LevelAlert     Level = gelf.LOG_ALERT
+	LevelCritical  Level = gelf.LOG_CRIT
+	LevelError     Level = gelf.LOG_ERR
+	LevelWarning   Level = gelf.LOG_WARNING
+	LevelNotice    Level = gelf.LOG_NOTICE
+	LevelInfo      Level = gelf.LOG_INFO
+	LevelDebug     Level = gelf.LOG_DEBUG
+)
+
+var levelNames = [...]string{"EMERG", "ALERT", "CRIT", "ERROR", "WARN", "NOTICE", "INFO", "DEBUG"}
+
+func (level Level) String() string {
+	if level < 0 || int(level) >= len(levelNames) {
+		return strconv.Itoa(int(level))
+	}
+	return levelNames[level]
+}
+
+// ParseLevel converts level name (as used in config strings) or syslog severity number to Level
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(s) {
+	case "emerg", "emergency":
+		return LevelEmergency, nil
+	case "alert":
+		return LevelAlert, nil
+	case "crit", "critical":
+		return LevelCritical, nil
+	case "err", "error":
+		return LevelError, nil
+	case "warn", "warning":
+		return LevelWarning, nil
+	case "notice":
+		return LevelNotice, nil
+	case "info":
+		return LevelInfo, nil
+	case "debug":
+		return LevelDebug, nil
+	}
+
+	level, err := strconv.Atoi(s)
+	if err != nil || level < int(LevelEmergency) || level > int(LevelDebug) {
+		return 0, errors.Errorf("Invalid log level '%s'", s)
+	}
+
+	return Level(level), nil
+}
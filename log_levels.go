@@ -0,0 +1,203 @@
+package gobase
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// LevelRegistry holds process-wide level overrides, changeable at runtime (signals, HTTP, gRPC).
+// Override for a kind takes precedence over global override, which takes precedence over level configured for a logger.
+type LevelRegistry struct {
+	mu       sync.Mutex // serializes writers, readers use snapshot
+	snapshot atomic.Pointer[levelOverrides]
+}
+
+type levelOverrides struct {
+	global    Level
+	hasGlobal bool
+	kinds     map[string]Level
+}
+
+// LevelsState is a view of the registry, as returned to admin endpoints
+type LevelsState struct {
+	Global *Level           `json:"global"`
+	Kinds  map[string]Level `json:"kinds"`
+}
+
+// Levels is process-wide registry consulted by all loggers
+var Levels = &LevelRegistry{}
+
+func (r *LevelRegistry) load() *levelOverrides {
+	if o := r.snapshot.Load(); o != nil {
+		return o
+	}
+	return &levelOverrides{}
+}
+
+// Effective returns level for messages of given kind, configured is level of the logger
+func (r *LevelRegistry) Effective(kind string, configured Level) Level {
+	o := r.load()
+
+	if level, ok := o.kinds[kind]; ok {
+		return level
+	}
+
+	if o.hasGlobal {
+		return o.global
+	}
+
+	return configured
+}
+
+// update applies change to a copy of current overrides (copy-on-write, Effective never blocks)
+func (r *LevelRegistry) update(change func(o *levelOverrides)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old := r.load()
+	o := &levelOverrides{
+		global:    old.global,
+		hasGlobal: old.hasGlobal,
+		kinds:     make(map[string]Level, len(old.kinds)),
+	}
+	for k, v := range old.kinds {
+		o.kinds[k] = v
+	}
+
+	change(o)
+
+	r.snapshot.Store(o)
+}
+
+func (r *LevelRegistry) SetGlobal(level Level) {
+	r.update(func(o *levelOverrides) {
+		o.global, o.hasGlobal = level, true
+	})
+	logLevelChange("global level set", "new_level", level)
+}
+
+// ResetGlobal removes global override, loggers use their configured level
+func (r *LevelRegistry) ResetGlobal() {
+	r.update(func(o *levelOverrides) {
+		o.hasGlobal = false
+	})
+	logLevelChange("global level reset")
+}
+
+func (r *LevelRegistry) SetKind(kind string, level Level) {
+	r.update(func(o *levelOverrides) {
+		o.kinds[kind] = level
+	})
+	logLevelChange("kind level set", "level_kind", kind, "new_level", level)
+}
+
+func (r *LevelRegistry) ResetKind(kind string) {
+	r.update(func(o *levelOverrides) {
+		delete(o.kinds, kind)
+	})
+	logLevelChange("kind level reset", "level_kind", kind)
+}
+
+// ResetAll removes all overrides
+func (r *LevelRegistry) ResetAll() {
+	r.update(func(o *levelOverrides) {
+		o.hasGlobal = false
+		o.kinds = map[string]Level{}
+	})
+	logLevelChange("all level overrides reset")
+}
+
+// ToggleDebug sets global level to debug, or resets it if it is already debug. Returns true if debug is now on.
+func (r *LevelRegistry) ToggleDebug() bool {
+	if o := r.load(); o.hasGlobal && o.global == LevelDebug {
+		r.ResetGlobal()
+		return false
+	}
+
+	r.SetGlobal(LevelDebug)
+	return true
+}
+
+func (r *LevelRegistry) State() LevelsState {
+	o := r.load()
+
+	state := LevelsState{Kinds: make(map[string]Level, len(o.kinds))}
+	if o.hasGlobal {
+		global := o.global
+		state.Global = &global
+	}
+	for k, v := range o.kinds {
+		state.Kinds[k] = v
+	}
+
+	return state
+}
+
+// Change is applied by admin endpoints: empty kind means global, level "reset" removes override
+func (r *LevelRegistry) Change(kind, level string) error {
+	if level == "reset" {
+		if kind == "" {
+			r.ResetGlobal()
+		} else {
+			r.ResetKind(kind)
+		}
+		return nil
+	}
+
+	v, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	if kind == "" {
+		r.SetGlobal(v)
+	} else {
+		r.SetKind(kind, v)
+	}
+
+	return nil
+}
+
+func logLevelChange(message string, keyvals ...any) {
+	// Warning, so that changes are visible with usual production levels. Level check is skipped,
+	// so that a change is logged even when it hides warnings.
+	logUnfiltered(DefaultLogger(), LevelWarning, "log_level", message, KeyvalsToFields(keyvals...))
+}
+
+func (level Level) MarshalText() ([]byte, error) {
+	return []byte(level.String()), nil
+}
+
+func (level *Level) UnmarshalText(text []byte) error {
+	v, err := ParseLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*level = v
+	return nil
+}
+
+// LevelsHandler is HTTP admin endpoint for the registry
+//
+//	GET: returns current overrides as JSON {"global": "DEBUG", "kinds": {"queue": "INFO"}}
+//	POST: changes override, form values: kind (empty for global), level (name or "reset")
+func LevelsHandler(r *LevelRegistry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodGet:
+		case http.MethodPost, http.MethodPut:
+			if err := r.Change(req.FormValue("kind"), req.FormValue("level")); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(r.State())
+	})
+}
@@ -0,0 +1,146 @@
+package gobase
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// LogLevelsServer is gRPC admin service for the level registry (service "gobase.LogLevels").
+// Messages are well-known types, so no .proto compilation is needed on either side:
+//
+//	GetLevels(google.protobuf.Empty) returns (google.protobuf.Struct) // {"global": "DEBUG", "kinds": {"queue": "INFO"}}
+//	SetLevel(google.protobuf.Struct) returns (google.protobuf.Struct) // request {"kind": "queue", "level": "debug"}, level may be "reset"
+type LogLevelsServer interface {
+	GetLevels(context.Context, *emptypb.Empty) (*structpb.Struct, error)
+	SetLevel(context.Context, *structpb.Struct) (*structpb.Struct, error)
+}
+
+type logLevelsServer struct {
+	registry *LevelRegistry
+}
+
+// RegisterLogLevelsServer adds "gobase.LogLevels" service changing given registry to the server
+func RegisterLogLevelsServer(s grpc.ServiceRegistrar, r *LevelRegistry) {
+	s.RegisterService(&logLevelsServiceDesc, &logLevelsServer{registry: r})
+}
+
+func (s *logLevelsServer) GetLevels(context.Context, *emptypb.Empty) (*structpb.Struct, error) {
+	return levelsStateToStruct(s.registry.State())
+}
+
+func (s *logLevelsServer) SetLevel(_ context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	fields := req.GetFields()
+
+	if err := s.registry.Change(fields["kind"].GetStringValue(), fields["level"].GetStringValue()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return levelsStateToStruct(s.registry.State())
+}
+
+func levelsStateToStruct(state LevelsState) (*structpb.Struct, error) {
+	kinds := make(map[string]any, len(state.Kinds))
+	for k, v := range state.Kinds {
+		kinds[k] = v.String()
+	}
+
+	var global any
+	if state.Global != nil {
+		global = state.Global.String()
+	}
+
+	return structpb.NewStruct(map[string]any{
+		"global": global,
+		"kinds":  kinds,
+	})
+}
+
+func levelsStateFromStruct(v *structpb.Struct) (LevelsState, error) {
+	state := LevelsState{Kinds: map[string]Level{}}
+
+	if global := v.GetFields()["global"].GetStringValue(); global != "" {
+		level, err := ParseLevel(global)
+		if err != nil {
+			return state, err
+		}
+		state.Global = &level
+	}
+
+	for k, lv := range v.GetFields()["kinds"].GetStructValue().GetFields() {
+		level, err := ParseLevel(lv.GetStringValue())
+		if err != nil {
+			return state, err
+		}
+		state.Kinds[k] = level
+	}
+
+	return state, nil
+}
+
+// GetLogLevels calls "gobase.LogLevels" service of a remote process
+func GetLogLevels(ctx context.Context, conn grpc.ClientConnInterface) (LevelsState, error) {
+	out := new(structpb.Struct)
+	if err := conn.Invoke(ctx, "/gobase.LogLevels/GetLevels", &emptypb.Empty{}, out); err != nil {
+		return LevelsState{}, err
+	}
+	return levelsStateFromStruct(out)
+}
+
+// SetLogLevel calls "gobase.LogLevels" service of a remote process, empty kind means global, level "reset" removes override
+func SetLogLevel(ctx context.Context, conn grpc.ClientConnInterface, kind, level string) (LevelsState, error) {
+	in, err := structpb.NewStruct(map[string]any{"kind": kind, "level": level})
+	if err != nil {
+		return LevelsState{}, err
+	}
+
+	out := new(structpb.Struct)
+	if err := conn.Invoke(ctx, "/gobase.LogLevels/SetLevel", in, out); err != nil {
+		return LevelsState{}, err
+	}
+	return levelsStateFromStruct(out)
+}
+
+var logLevelsServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gobase.LogLevels",
+	HandlerType: (*LogLevelsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetLevels",
+			Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+				in := new(emptypb.Empty)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				if interceptor == nil {
+					return srv.(LogLevelsServer).GetLevels(ctx, in)
+				}
+				info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/gobase.LogLevels/GetLevels"}
+				return interceptor(ctx, in, info, func(ctx context.Context, req any) (any, error) {
+					return srv.(LogLevelsServer).GetLevels(ctx, req.(*emptypb.Empty))
+				})
+			},
+		},
+		{
+			MethodName: "SetLevel",
+			Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+				in := new(structpb.Struct)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				if interceptor == nil {
+					return srv.(LogLevelsServer).SetLevel(ctx, in)
+				}
+				info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/gobase.LogLevels/SetLevel"}
+				return interceptor(ctx, in, info, func(ctx context.Context, req any) (any, error) {
+					return srv.(LogLevelsServer).SetLevel(ctx, req.(*structpb.Struct))
+				})
+			},
+		},
+	},
+	Streams: []grpc.StreamDesc{},
+}
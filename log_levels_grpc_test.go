@@ -0,0 +1,62 @@
+package gobase
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+func TestLogLevelsServer(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &LevelRegistry{}
+	server := grpc.NewServer()
+	RegisterLogLevelsServer(server, r)
+	go server.Serve(l)
+	defer server.Stop()
+
+	conn, err := grpc.NewClient(l.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx := context.Background()
+
+	if _, err := SetLogLevel(ctx, conn, "", "warning"); err != nil {
+		t.Fatal(err)
+	}
+	state, err := SetLogLevel(ctx, conn, "queue", "debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Global == nil || *state.Global != LevelWarning || state.Kinds["queue"] != LevelDebug {
+		t.Errorf("unexpected state %+v", state)
+	}
+	if r.Effective("queue", LevelInfo) != LevelDebug {
+		t.Error("kind level not set")
+	}
+
+	if _, err := SetLogLevel(ctx, conn, "queue", "loud"); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("invalid level: %v", err)
+	}
+
+	if _, err := SetLogLevel(ctx, conn, "", "reset"); err != nil {
+		t.Fatal(err)
+	}
+	state, err = GetLogLevels(ctx, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Global != nil || state.Kinds["queue"] != LevelDebug {
+		t.Errorf("global level not reset: %+v", state)
+	}
+}
@@ -0,0 +1,36 @@
+//go:build unix
+
+package gobase
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// WatchLevelSignals changes Levels registry on signals until ctx is done:
+//
+//	SIGUSR1: toggle global debug level
+//	SIGUSR2: reset all overrides
+func WatchLevelSignals(ctx context.Context) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGUSR1, syscall.SIGUSR2)
+
+	go func() {
+		defer signal.Stop(c)
+
+		for {
+			select {
+			case sig := <-c:
+				if sig == syscall.SIGUSR1 {
+					Levels.ToggleDebug()
+				} else {
+					Levels.ResetAll()
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
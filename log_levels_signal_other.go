@@ -0,0 +1,11 @@
+//go:build !unix
+
+package gobase
+
+import (
+	"context"
+)
+
+// WatchLevelSignals does nothing on platforms without SIGUSR1/SIGUSR2
+func WatchLevelSignals(ctx context.Context) {
+}
@@ -0,0 +1,115 @@
+package gobase
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestLevelRegistryOverrides(t *testing.T) {
+	r := &LevelRegistry{}
+
+	if level := r.Effective("queue", LevelInfo); level != LevelInfo {
+		t.Errorf("level without overrides %s", level)
+	}
+
+	r.SetGlobal(LevelWarning)
+	r.SetKind("queue", LevelDebug)
+	if level := r.Effective("queue", LevelInfo); level != LevelDebug {
+		t.Errorf("kind override not used: %s", level)
+	}
+	if level := r.Effective("db", LevelInfo); level != LevelWarning {
+		t.Errorf("global override not used: %s", level)
+	}
+
+	r.ResetKind("queue")
+	if level := r.Effective("queue", LevelInfo); level != LevelWarning {
+		t.Errorf("kind override not reset: %s", level)
+	}
+
+	r.SetKind("queue", LevelDebug)
+	r.ResetAll()
+	if level := r.Effective("queue", LevelInfo); level != LevelInfo {
+		t.Errorf("overrides not reset: %s", level)
+	}
+
+	if !r.ToggleDebug() || r.Effective("db", LevelInfo) != LevelDebug || r.ToggleDebug() || r.Effective("db", LevelInfo) != LevelInfo {
+		t.Error("debug not toggled")
+	}
+
+	if err := r.Change("queue", "loud"); err == nil {
+		t.Error("invalid level accepted")
+	}
+}
+
+func TestLevelChangeLoggedWhenItHidesWarnings(t *testing.T) {
+	defer setDefaultLogger(DefaultLogger())
+	defer Levels.ResetAll()
+
+	logger := NewRecordingLogger()
+	logger.SetAsDefault()
+
+	Levels.SetGlobal(LevelError)
+
+	logger.AssertLogged(t, LevelWarning, "log_level", "global level set", FieldEquals("new_level", LevelError))
+	logger.AssertNotLogged(t, LevelWarning, "log_level", "", HasField("level"))
+}
+
+func TestLevelsHandler(t *testing.T) {
+	r := &LevelRegistry{}
+	server := httptest.NewServer(LevelsHandler(r))
+	defer server.Close()
+
+	resp, err := http.PostForm(server.URL, url.Values{"kind": {"queue"}, "level": {"debug"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state map[string]any
+	json.NewDecoder(resp.Body).Decode(&state)
+	resp.Body.Close()
+
+	if state["global"] != nil || state["kinds"].(map[string]any)["queue"] != "DEBUG" {
+		t.Errorf("unexpected state %v", state)
+	}
+	if r.Effective("queue", LevelInfo) != LevelDebug {
+		t.Error("kind level not set")
+	}
+
+	resp, err = http.PostForm(server.URL, url.Values{"level": {"loud"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid level: status %d", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, server.URL, nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE: status %d", resp.StatusCode)
+	}
+
+	resp, err = http.PostForm(server.URL, url.Values{"kind": {"queue"}, "level": {"reset"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	resp, err = http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state = nil
+	json.NewDecoder(resp.Body).Decode(&state)
+	resp.Body.Close()
+	if len(state["kinds"].(map[string]any)) != 0 {
+		t.Errorf("kind level not reset: %v", state)
+	}
+}
@@ -1,59 +1,33 @@
 package gobase
 
 import (
-	"fmt"
-	"os"
-	"runtime/debug"
-	"time"
-
 	"github.com/go-faster/errors"
-	"gopkg.in/Graylog2/go-gelf.v2/gelf"
 )
 
+// LogPanic recovers from panic and reports it (see PanicReport), must be deferred:
+//
+//	defer LogPanic(logger, "queue")
 func LogPanic(l Logger, kind string) {
 	if r := recover(); r != nil {
-		rs := fmt.Sprintf("recovered from panic: %s", r)
-		ss := fmt.Sprintf("stacktrace from panic: \n%s", debug.Stack())
-		fmt.Println(rs)
-		fmt.Println(ss)
-		if l != nil {
-			l.Message(gelf.LOG_CRIT, kind, "panic (err, stacktrace)", map[string]any{
-				"err":        rs,
-				"stacktrace": ss,
-			})
-		}
+		reportPanic(l, kind, "", r)
 	}
 }
 
+// LogPanicErr recovers from panic, reports it and sets *errOut to error with errorTitle (panic details are only logged)
 func LogPanicErr(errOut *error, l Logger, kind string, errorTitle string) {
 	if r := recover(); r != nil {
 		*errOut = errors.New("panic (details hidden): " + errorTitle)
-		rs := fmt.Sprintf("recovered from panic: %s", r)
-		ss := fmt.Sprintf("stacktrace from panic: \n%s", debug.Stack())
-		fmt.Println(rs)
-		fmt.Println(ss)
-		if l != nil {
-			l.Message(gelf.LOG_CRIT, kind, "panic (err, stacktrace): "+errorTitle, map[string]any{
-				"err":        rs,
-				"stacktrace": ss,
-			})
-		}
+		reportPanic(l, kind, errorTitle, r)
 	}
 }
 
+// LogPanicExit recovers from panic, reports it and exits the process with Exit
+// (shutdown hooks run with a deadline, exit code is set with SetPanicExit, 1 by default).
+// If ExitFunc returns (replaced in tests), the panic continues with the same value.
 func LogPanicExit(l Logger, kind string) {
 	if r := recover(); r != nil {
-		rs := fmt.Sprintf("recovered from panic (exiting): %s", r)
-		ss := fmt.Sprintf("stacktrace from panic: \n%s", debug.Stack())
-		fmt.Println(rs)
-		fmt.Println(ss)
-		if l != nil {
-			l.Message(gelf.LOG_CRIT, kind, "panic (err, stacktrace)", map[string]any{
-				"err":        rs,
-				"stacktrace": ss,
-			})
-		}
-		time.Sleep(time.Second * 5)
-		os.Exit(1)
+		reportPanic(l, kind, "", r)
+		Exit(int(panicExitCode.Load()))
+		panic(r)
 	}
 }
@@ -0,0 +1,54 @@
+package gobase
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLogPanic(t *testing.T) {
+	logger := NewRecordingLogger()
+
+	func() {
+		defer LogPanic(logger, "worker")
+		var m map[string]int
+		m["x"] = 1
+	}()
+
+	logger.AssertLogged(t, LevelCritical, "worker", "assignment to entry in nil map",
+		FieldContains("function", "TestLogPanic"),
+		HasField("panic_frames"),
+		HasField("error_type"),
+	)
+}
+
+func TestLogPanicErr(t *testing.T) {
+	logger := NewRecordingLogger().AddRequestID("req1")
+	recorder := logger.(*GelfLogger)
+
+	run := func() (err error) {
+		defer LogPanicErr(&err, logger, "handler", "load chats")
+		panic("secret details")
+	}
+
+	err := run()
+	if err == nil || strings.Contains(err.Error(), "secret details") || !strings.Contains(err.Error(), "load chats") {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	messages := recorder.writer.(*RecordingWriter).messages
+	if len(messages) != 1 || messages[0].RequestID != "req1" || messages[0].Fields["panic_title"] != "load chats" {
+		t.Errorf("unexpected messages %v", messages)
+	}
+}
+
+func TestLogPanicWithoutPanic(t *testing.T) {
+	logger := NewRecordingLogger()
+
+	func() {
+		defer LogPanic(logger, "worker")
+	}()
+
+	if n := len(logger.Messages()); n != 0 {
+		t.Errorf("%d messages logged without panic", n)
+	}
+}
@@ -6,17 +6,38 @@ import (
 	"io"
 	"log"
 	"os"
+	"sync/atomic"
 )
 
-var defaultLogger Logger = &DummyLogger{}
+// defaultLogger is read concurrently by handlers, interceptors and signal handlers, see DefaultLogger
+var defaultLogger atomic.Pointer[Logger]
+
+func init() {
+	setDefaultLogger(&DummyLogger{})
+}
+
+func setDefaultLogger(l Logger) {
+	defaultLogger.Store(&l)
+}
+
+// logUnfiltered sends message skipping level and sampling checks of GelfLogger, for messages requested explicitly
+func logUnfiltered(l Logger, level Level, kind, message string, fields map[string]any) {
+	if gl, ok := l.(interface {
+		message(callerSkip int, level Level, kind string, message string, fields ...map[string]any) bool
+	}); ok {
+		gl.message(1, level, kind, message, fields)
+		return
+	}
+	l.Message(level, kind, message, fields)
+}
 
 func LogErrorln(ss ...any) {
 	s := fmt.Sprintln(ss...) + "\n"
 
-	if defaultLogger == nil {
+	if l := DefaultLogger(); l == nil {
 		os.Stderr.Write([]byte(s))
 	} else {
-		defaultLogger.Write([]byte(s))
+		l.Write([]byte(s))
 	}
 }
 
@@ -28,10 +49,29 @@ func LogErrorf(errf string, arg ...any) {
 type Logger interface {
 	io.Writer
 	Close() error
-	Message(level int32, kind string, message string, extras ...map[string]any) bool
+	Message(level Level, kind string, message string, extras ...map[string]any) bool
+	Enabled(level Level, kind string) bool
+
+	// Level helpers, keyvals are alternating keys and values ("user_id", 1, "chat", "x"),
+	// a map[string]any in place of a key is merged as is, an error is expanded with ErrorField.
+	Debug(kind, message string, keyvals ...any)
+	Debugf(kind, format string, args ...any)
+	Info(kind, message string, keyvals ...any)
+	Infof(kind, format string, args ...any)
+	Warn(kind, message string, keyvals ...any)
+	Warnf(kind, format string, args ...any)
+	Error(kind, message string, keyvals ...any)
+	Errorf(kind, format string, args ...any)
+	Critical(kind, message string, keyvals ...any)
+	Criticalf(kind, format string, args ...any)
+
+	// Start begins timed operation, its End logs duration and outcome (see Operation)
+	Start(kind, message string, keyvals ...any) *Operation
+
 	AddRequestID(requestUid string, fields ...map[string]any) Logger
 	SetField(key string, value any)
 	SetFields(map[string]any)
+	Fields() map[string]any
 	SetAsDefault() Logger
 }
 
@@ -43,8 +83,16 @@ func (DummyLogger) Close() error {
 	return nil
 }
 
-func (DummyLogger) Message(level int32, kind string, message string, extras ...map[string]any) bool {
-	if data, err := json.MarshalIndent(extras, "", "    "); err != nil {
+func (DummyLogger) Message(level Level, kind string, message string, extras ...map[string]any) bool {
+	message = DefaultRedactor.RedactString(message)
+
+	// extras of the caller are not modified
+	redacted := make([]map[string]any, len(extras))
+	for i := range extras {
+		redacted[i] = DefaultRedactor.RedactFields(extras[i])
+	}
+
+	if data, err := json.MarshalIndent(redacted, "", "    "); err != nil {
 		log.Println("WARN log not sent", level, kind, message)
 	} else {
 		log.Println("WARN log not sent", level, kind, message, string(data))
@@ -53,6 +101,54 @@ func (DummyLogger) Message(level int32, kind string, message string, extras ...m
 	return true
 }
 
+func (DummyLogger) Enabled(Level, string) bool {
+	return true
+}
+
+func (dummy DummyLogger) Debug(kind, message string, keyvals ...any) {
+	dummy.Message(LevelDebug, kind, message, KeyvalsToFields(keyvals...))
+}
+
+func (dummy DummyLogger) Debugf(kind, format string, args ...any) {
+	dummy.Message(LevelDebug, kind, fmt.Sprintf(format, args...))
+}
+
+func (dummy DummyLogger) Info(kind, message string, keyvals ...any) {
+	dummy.Message(LevelInfo, kind, message, KeyvalsToFields(keyvals...))
+}
+
+func (dummy DummyLogger) Infof(kind, format string, args ...any) {
+	dummy.Message(LevelInfo, kind, fmt.Sprintf(format, args...))
+}
+
+func (dummy DummyLogger) Warn(kind, message string, keyvals ...any) {
+	dummy.Message(LevelWarning, kind, message, KeyvalsToFields(keyvals...))
+}
+
+func (dummy DummyLogger) Warnf(kind, format string, args ...any) {
+	dummy.Message(LevelWarning, kind, fmt.Sprintf(format, args...))
+}
+
+func (dummy DummyLogger) Error(kind, message string, keyvals ...any) {
+	dummy.Message(LevelError, kind, message, KeyvalsToFields(keyvals...))
+}
+
+func (dummy DummyLogger) Errorf(kind, format string, args ...any) {
+	dummy.Message(LevelError, kind, fmt.Sprintf(format, args...))
+}
+
+func (dummy DummyLogger) Critical(kind, message string, keyvals ...any) {
+	dummy.Message(LevelCritical, kind, message, KeyvalsToFields(keyvals...))
+}
+
+func (dummy DummyLogger) Criticalf(kind, format string, args ...any) {
+	dummy.Message(LevelCritical, kind, fmt.Sprintf(format, args...))
+}
+
+func (dummy DummyLogger) Start(kind, message string, keyvals ...any) *Operation {
+	return newOperation(dummy, kind, message, keyvals)
+}
+
 func (dummy DummyLogger) AddRequestID(string, ...map[string]any) Logger {
 	return dummy
 }
@@ -63,6 +159,10 @@ func (DummyLogger) SetField(string, any) {
 func (DummyLogger) SetFields(map[string]any) {
 }
 
+func (DummyLogger) Fields() map[string]any {
+	return map[string]any{}
+}
+
 func (dummy DummyLogger) Write(p []byte) (int, error) {
 	return 0, nil
 }
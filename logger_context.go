@@ -0,0 +1,65 @@
+package gobase
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+type loggerContextKey struct{}
+
+// WithLogger returns context carrying the logger, retrieve it with LoggerFrom
+func WithLogger(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, l)
+}
+
+// LoggerFrom returns logger carried by context, or the default logger (see SetAsDefault)
+func LoggerFrom(ctx context.Context) Logger {
+	if l, ok := loggerFromContext(ctx); ok {
+		return l
+	}
+	return DefaultLogger()
+}
+
+func loggerFromContext(ctx context.Context) (Logger, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	l, ok := ctx.Value(loggerContextKey{}).(Logger)
+	return l, ok && l != nil
+}
+
+// DefaultLogger returns logger set by SetAsDefault (DummyLogger if none)
+func DefaultLogger() Logger {
+	return *defaultLogger.Load()
+}
+
+// ContextLoggerUnaryInterceptor places the logger in handler context, unless context already carries one
+func ContextLoggerUnaryInterceptor(l Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if _, ok := loggerFromContext(ctx); !ok {
+			ctx = WithLogger(ctx, l)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// ContextLoggerStreamInterceptor places the logger in stream context, unless context already carries one
+func ContextLoggerStreamInterceptor(l Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if _, ok := loggerFromContext(ss.Context()); !ok {
+			ss = &contextServerStream{ServerStream: ss, ctx: WithLogger(ss.Context(), l)}
+		}
+		return handler(srv, ss)
+	}
+}
+
+// contextServerStream overrides context of the stream
+type contextServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextServerStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,70 @@
+package gobase
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"runtime"
+)
+
+// Function of LogBridge logging lines, which is not their caller
+var writeLogLinesFunction = runtime.FuncForPC(reflect.ValueOf(writeLogLines).Pointer()).Name()
+
+// KeyvalsToFields converts alternating keys and values to fields map.
+// A map[string]any in place of a key is merged as is, an error in place of a key is expanded with ErrorField,
+// a key without value gets "!MISSING" value.
+func KeyvalsToFields(keyvals ...any) map[string]any {
+	fields := make(map[string]any, len(keyvals)/2)
+
+	for i := 0; i < len(keyvals); i++ {
+		if m, ok := keyvals[i].(map[string]any); ok {
+			for k, v := range m {
+				fields[k] = v
+			}
+			continue
+		}
+
+		if err, ok := keyvals[i].(error); ok {
+			for k, v := range ErrorField(err) {
+				fields[k] = v
+			}
+			continue
+		}
+
+		key, ok := keyvals[i].(string)
+		if !ok {
+			key = fmt.Sprint(keyvals[i])
+		}
+
+		if i+1 < len(keyvals) {
+			fields[key] = keyvals[i+1]
+			i++
+		} else {
+			fields[key] = "!MISSING"
+		}
+	}
+
+	return fields
+}
+
+// addCallerFields sets "file", "line" and "function" fields from caller frame, unless already set
+// or the caller is LogBridge. skip is number of frames above addCallerFields caller.
+func addCallerFields(fields map[string]any, skip int) {
+	if _, ok := fields["file"]; ok {
+		return
+	}
+
+	pc := make([]uintptr, 1)
+	if runtime.Callers(skip+2, pc) == 0 {
+		return
+	}
+
+	frame, _ := runtime.CallersFrames(pc).Next()
+	if frame.Function == writeLogLinesFunction {
+		return
+	}
+
+	fields["file"] = filepath.Base(filepath.Dir(frame.File)) + "/" + filepath.Base(frame.File)
+	fields["line"] = frame.Line
+	fields["function"] = frame.Function
+}
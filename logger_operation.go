@@ -0,0 +1,114 @@
+package gobase
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Operation measures duration of a piece of work started with Logger.Start, End logs it as a single message:
+//
+//	op := logger.Start("db", "load chats", "user_id", 1).Slow(100 * time.Millisecond)
+//	chats, err := load()
+//	op.End(err)
+//
+// The message has "duration_ms" and "outcome" ("ok" or "error", with ErrorField fields) and caller of Start.
+// Failed operations are logged at LevelError, slow ones at LevelWarning with "slow" field,
+// others at operation level (LevelInfo by default) unless slow threshold is set.
+type Operation struct {
+	logger  Logger
+	kind    string
+	message string
+	started time.Time
+	ended   atomic.Bool
+
+	mu     sync.Mutex
+	fields map[string]any
+	level  Level
+	slow   time.Duration
+}
+
+func newOperation(logger Logger, kind, message string, keyvals []any) *Operation {
+	fields := KeyvalsToFields(keyvals...)
+	addCallerFields(fields, 2)
+
+	return &Operation{
+		logger:  logger,
+		kind:    kind,
+		message: message,
+		started: time.Now(),
+		fields:  fields,
+		level:   LevelInfo,
+	}
+}
+
+// Level sets level of message of successful operation
+func (op *Operation) Level(level Level) *Operation {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+
+	op.level = level
+	return op
+}
+
+// Slow makes successful operations finished in less than threshold not logged, 0 logs all
+func (op *Operation) Slow(threshold time.Duration) *Operation {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+
+	op.slow = threshold
+	return op
+}
+
+// SetField adds field to the message logged by End
+func (op *Operation) SetField(key string, value any) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+
+	op.fields[key] = value
+}
+
+// Elapsed returns time since operation start
+func (op *Operation) Elapsed() time.Duration {
+	return time.Since(op.started)
+}
+
+// End logs operation duration and outcome, only the first call logs. Returns operation duration.
+func (op *Operation) End(err error) time.Duration {
+	duration := time.Since(op.started)
+
+	if !op.ended.CompareAndSwap(false, true) {
+		return duration
+	}
+
+	op.mu.Lock()
+	defer op.mu.Unlock()
+
+	level := op.level
+	fields := make(map[string]any, len(op.fields)+2)
+	for k, v := range op.fields {
+		fields[k] = v
+	}
+	fields["duration_ms"] = duration.Milliseconds()
+
+	switch {
+	case err != nil:
+		level = LevelError
+		fields["outcome"] = "error"
+		for k, v := range ErrorField(err) {
+			fields[k] = v
+		}
+	case op.slow > 0 && duration < op.slow:
+		return duration
+	case op.slow > 0:
+		level = LevelWarning
+		fields["outcome"] = "ok"
+		fields["slow"] = true
+	default:
+		fields["outcome"] = "ok"
+	}
+
+	op.logger.Message(level, op.kind, op.message, fields)
+
+	return duration
+}
@@ -0,0 +1,212 @@
+package gobase
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-faster/errors"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"gopkg.in/Graylog2/go-gelf.v2/gelf"
+)
+
+// ErrMessageDropped is returned (wrapped) by a MessageWriter discarding message on purpose, e.g. when its queue is full.
+// Such messages are counted as dropped, not failed.
+var ErrMessageDropped = errors.New("message dropped")
+
+// Default time a sink can fail before it is reported as unhealthy, see GelfLogger.SetFailureThreshold
+const DefaultSinkFailureThreshold = 30 * time.Second
+
+// LoggerStats are counters of a logger sink, shared by loggers made with AddRequestID.
+// Sinks delivering in background (OTLPWriter) count queued messages as sent, and messages of failed exports as failed.
+type LoggerStats struct {
+	Sink          string    // type of MessageWriter
+	Sent          uint64    // messages written
+	Failed        uint64    // messages the sink returned error for
+	Dropped       uint64    // messages suppressed by sampler or dropped by the sink
+	Bytes         uint64    // size of written messages as serialized by the sink (not counted by Graylog writers)
+	LastError     string    // last error returned by the sink
+	LastErrorTime time.Time // zero if there were no errors
+	FailingSince  time.Time // first error of failures not followed by a successful write, zero if sink works
+	Healthy       bool      // sink is not failing longer than failure threshold
+}
+
+// StatsReporter is a logger reporting its sink stats
+type StatsReporter interface {
+	Stats() LoggerStats
+}
+
+// asyncSink is MessageWriter delivering messages in background, it reports delivery results to sink stats
+type asyncSink interface {
+	setSinkStats(s *sinkStats)
+}
+
+// sizedWriter is MessageWriter returning size of the message it serialized, counted in LoggerStats.Bytes
+type sizedWriter interface {
+	writeMessage(m *gelf.Message) (int, error)
+}
+
+// writeMessage writes m to w, returns its size if w reports it
+func writeMessage(w MessageWriter, m *gelf.Message) (int, error) {
+	if sw, ok := w.(sizedWriter); ok {
+		return sw.writeMessage(m)
+	}
+	return 0, w.WriteMessage(m)
+}
+
+type sinkStats struct {
+	sink                       string
+	sent, failed, dropped, bts atomic.Uint64
+	threshold                  atomic.Int64 // time.Duration
+	failing                    atomic.Bool  // failingSince is set, checked without lock on every write
+
+	mu            sync.Mutex
+	lastError     string
+	lastErrorTime time.Time
+	failingSince  time.Time
+	lastWarning   time.Time
+}
+
+func newSinkStats(writer MessageWriter) *sinkStats {
+	s := &sinkStats{sink: fmt.Sprintf("%T", writer)}
+	s.threshold.Store(int64(DefaultSinkFailureThreshold))
+	return s
+}
+
+// written counts successfully written message of size bytes, reports whether sink recovered from failures reported by warning
+func (s *sinkStats) written(size int) (recovered bool, failedFor time.Duration) {
+	s.sent.Add(1)
+	s.bts.Add(uint64(size))
+	return s.succeeded()
+}
+
+// succeeded ends failures of the sink, reports whether it recovered from failures reported by warning
+func (s *sinkStats) succeeded() (recovered bool, failedFor time.Duration) {
+	if !s.failing.Load() {
+		return false, 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failingSince.IsZero() {
+		return false, 0
+	}
+
+	recovered, failedFor = !s.lastWarning.IsZero(), time.Since(s.failingSince)
+	s.failingSince, s.lastWarning = time.Time{}, time.Time{}
+	s.failing.Store(false)
+
+	return recovered, failedFor
+}
+
+// writeFailed counts failed or dropped message
+func (s *sinkStats) writeFailed(err error) {
+	if errors.Is(err, ErrMessageDropped) {
+		s.dropped.Add(1)
+		return
+	}
+
+	s.failures(1, err)
+}
+
+// exported counts n messages of size bytes delivered by asyncSink in background, err is nil if they were delivered
+func (s *sinkStats) exported(n, size int, err error) {
+	if err != nil {
+		s.failures(uint64(n), err)
+		return
+	}
+
+	s.bts.Add(uint64(size))
+
+	if recovered, failedFor := s.succeeded(); recovered {
+		// Logging it through the logger could loop back to the sink
+		stderrLog.Printf("WARN logger sink %s recovered after failing for %s", s.sink, failedFor.Round(time.Millisecond))
+	}
+}
+
+// failures counts n failed messages and prints warning to stderr, at most once per threshold,
+// when sink is failing longer than threshold
+func (s *sinkStats) failures(n uint64, err error) {
+	failed := s.failed.Add(n)
+	threshold := time.Duration(s.threshold.Load())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.lastError, s.lastErrorTime = err.Error(), now
+	if s.failingSince.IsZero() {
+		s.failingSince = now
+		s.failing.Store(true)
+	}
+
+	if now.Sub(s.failingSince) >= threshold && now.Sub(s.lastWarning) >= threshold {
+		s.lastWarning = now
+		stderrLog.Printf("WARN logger sink %s is failing for %s, %d messages failed in total, last error: %s",
+			s.sink, now.Sub(s.failingSince).Round(time.Millisecond), failed, err)
+	}
+}
+
+func (s *sinkStats) snapshot() LoggerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return LoggerStats{
+		Sink:          s.sink,
+		Sent:          s.sent.Load(),
+		Failed:        s.failed.Load(),
+		Dropped:       s.dropped.Load(),
+		Bytes:         s.bts.Load(),
+		LastError:     s.lastError,
+		LastErrorTime: s.lastErrorTime,
+		FailingSince:  s.failingSince,
+		Healthy:       s.failingSince.IsZero() || time.Since(s.failingSince) < time.Duration(s.threshold.Load()),
+	}
+}
+
+// Stats returns counters of the logger sink
+func (logger *GelfLogger) Stats() LoggerStats {
+	return logger.stats.snapshot()
+}
+
+// SetFailureThreshold sets how long the sink can fail before it is reported unhealthy
+// and a warning is printed to stderr (repeated every threshold while failing)
+func (logger *GelfLogger) SetFailureThreshold(threshold time.Duration) {
+	logger.stats.threshold.Store(int64(threshold))
+}
+
+// ReportLoggerHealth sets serving status of service in gRPC health server every interval until ctx is done:
+// NOT_SERVING while any of loggers has unhealthy sink (see LoggerStats), SERVING otherwise.
+// Use service name "" to report overall server health.
+func ReportLoggerHealth(ctx context.Context, hs *health.Server, service string, interval time.Duration, loggers ...StatsReporter) {
+	report := func() {
+		status := healthpb.HealthCheckResponse_SERVING
+		for _, l := range loggers {
+			if !l.Stats().Healthy {
+				status = healthpb.HealthCheckResponse_NOT_SERVING
+				break
+			}
+		}
+		hs.SetServingStatus(service, status)
+	}
+
+	report()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				report()
+			}
+		}
+	}()
+}
@@ -0,0 +1,82 @@
+package gobase
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/go-faster/errors"
+	"gopkg.in/Graylog2/go-gelf.v2/gelf"
+)
+
+type droppingWriter struct{}
+
+func (droppingWriter) WriteMessage(*gelf.Message) error {
+	return errors.Wrap(ErrMessageDropped, "queue is full")
+}
+
+func (droppingWriter) Close() error {
+	return nil
+}
+
+func TestDroppedMessageNotPrinted(t *testing.T) {
+	defer stderrLog.SetOutput(stderrLog.Writer())
+	var out bytes.Buffer
+	stderrLog.SetOutput(&out)
+
+	logger := newGelfLogger(droppingWriter{}, "test", "test")
+	logger.Info("test", "message")
+
+	if out.Len() != 0 {
+		t.Errorf("dropped message printed: %s", out.String())
+	}
+	if stats := logger.Stats(); stats.Dropped != 1 || stats.Failed != 0 || !stats.Healthy {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestLoggerStatsBytes(t *testing.T) {
+	var out bytes.Buffer
+	logger := newGelfLogger(NewJSONWriter(&out), "test", "test")
+	logger.Info("test", "message", "user_id", 1)
+	logger.Info("test", "message")
+
+	if stats := logger.Stats(); stats.Sent != 2 || stats.Bytes != uint64(out.Len()) {
+		t.Errorf("stats %+v, written %d bytes", stats, out.Len())
+	}
+}
+
+// switchingWriter fails while failing is set
+type switchingWriter struct {
+	failing bool
+}
+
+func (w *switchingWriter) WriteMessage(*gelf.Message) error {
+	if w.failing {
+		return errors.New("sink is down")
+	}
+	return nil
+}
+
+func (w *switchingWriter) Close() error {
+	return nil
+}
+
+func TestLoggerStatsRecovered(t *testing.T) {
+	defer stderrLog.SetOutput(stderrLog.Writer())
+	stderrLog.SetOutput(&bytes.Buffer{})
+
+	w := &switchingWriter{failing: true}
+	logger := newGelfLogger(w, "test", "test")
+	logger.SetFailureThreshold(0)
+
+	logger.Info("test", "message")
+	if stats := logger.Stats(); stats.Healthy || stats.FailingSince.IsZero() {
+		t.Errorf("failing sink reported healthy: %+v", stats)
+	}
+
+	w.failing = false
+	logger.Info("test", "message")
+	if stats := logger.Stats(); !stats.Healthy || !stats.FailingSince.IsZero() || stats.Failed != 1 {
+		t.Errorf("recovered sink reported failing: %+v", stats)
+	}
+}
@@ -15,8 +15,9 @@ import (
 )
 
 type ClientConfig struct {
-	Addr string `mapstructure:"addr"`
-	Port string `mapstructure:"port"`
+	Addr    string `mapstructure:"addr"`
+	Port    string `mapstructure:"port"`
+	Logging string `mapstructure:"logging"`
 }
 
 type ClientTLS struct {
@@ -44,6 +45,9 @@ func (s ClientConfig) GetDialAddress() string {
 //
 //	"test;key1=value;key2=other value;"
 //
+// Logging interceptors (request ID sent in metadata, method, status code, duration; see LoggingUnaryClientInterceptor)
+// are installed with the default logger, unless "logging=off" is set.
+//
 // serviceName: locates config values by key "GRPC_SERVICE_serviceName
 // globalConfig: config map, from where to read the string. If nil, environment variables are used
 // opts: other gRPC server options to use
@@ -56,6 +60,13 @@ func NewClientFromConfig(serviceName string, globalConfig map[string]string, opt
 		return nil, err
 	}
 
+	if config.Logging != "off" {
+		opts = append([]grpc.DialOption{
+			grpc.WithChainUnaryInterceptor(LoggingUnaryClientInterceptor(nil)),
+			grpc.WithChainStreamInterceptor(LoggingStreamClientInterceptor(nil)),
+		}, opts...)
+	}
+
 	conn, err := grpc.NewClient(config.GetDialAddress(), append(opts, securityOption)...)
 
 	return conn, err
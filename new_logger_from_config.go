@@ -0,0 +1,455 @@
+package gobase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-faster/errors"
+	"github.com/mitchellh/mapstructure"
+	"gopkg.in/Graylog2/go-gelf.v2/gelf"
+)
+
+type LoggerConfig struct {
+	Addr           string `mapstructure:"addr"`
+	Port           string `mapstructure:"port"`
+	Level          string `mapstructure:"level"`
+	Facility       string `mapstructure:"facility"`
+	Hostname       string `mapstructure:"hostname"`
+	Stderr         string `mapstructure:"stderr"`
+	Path           string `mapstructure:"path"`
+	Format         string `mapstructure:"format"`
+	Network        string `mapstructure:"network"`
+	SyslogFacility string `mapstructure:"syslog-facility"`
+	Redact         string `mapstructure:"redact"`
+	Sample         string `mapstructure:"sample"`
+	SampleLevel    string `mapstructure:"sample-level"`
+	MaxSize        string `mapstructure:"max-size"`
+	MaxAge         string `mapstructure:"max-age"`
+	MaxBackups     string `mapstructure:"max-backups"`
+	Compress       string `mapstructure:"compress"`
+	Reopen         string `mapstructure:"reopen"`
+	Protocol       string `mapstructure:"protocol"`
+	URL            string `mapstructure:"url"`
+	ReportDSN      string `mapstructure:"report-dsn"`
+	ReportLevel    string `mapstructure:"report-level"`
+	ReportSample   string `mapstructure:"report-sample"`
+	ReportRelease  string `mapstructure:"report-release"`
+	ReportEnv      string `mapstructure:"report-env"`
+	ReportQueue    string `mapstructure:"report-queue"`
+}
+
+// loggerModeOptions are options of every mode, besides common ones (see NewLoggerFromConfig)
+var loggerModeOptions = map[string][]string{
+	"gelf-tcp": {"addr", "port"},
+	"gelf-udp": {"addr", "port"},
+	"json":     {},
+	"console":  {},
+	"file":     {"path", "format", "max-size", "max-age", "max-backups", "compress", "reopen"},
+	"syslog":   {"addr", "port", "path", "network", "syslog-facility"},
+	"otlp":     {"addr", "port", "protocol", "url"},
+	"none":     {},
+}
+
+var loggerCommonOptions = []string{
+	"level", "facility", "hostname", "stderr", "redact", "sample", "sample-level",
+	"report-dsn", "report-level", "report-sample", "report-release", "report-env", "report-queue",
+}
+
+func (c LoggerConfig) GetAddress() string {
+	return fmt.Sprintf("%s:%s", c.Addr, c.Port)
+}
+
+// NewLoggerFromConfig creates logger from environmental config (or custom config source)
+//
+// Config is read using key=value; pairs in key LOG_name value:
+// A string must begin with first argument with no value (logger mode)
+// Key-value pairs are separated with ';'
+//
+// Example:
+//
+//	"gelf-tcp;addr=graylog;port=12201;level=info;facility=bot;stderr=err"
+//
+// Modes:
+//
+//	gelf-tcp, gelf-udp: send to Graylog at addr:port
+//	json, console: write to stdout (JSON-lines or human-readable)
+//	file: append to file at path, format=json (default) or format=text,
+//	      rotated by max-size (bytes, K/M/G suffix) and max-age (e.g. "24h"),
+//	      keeping max-backups files, gzip compressed unless compress=0 (see FileWriter),
+//	      reopen=sighup reopens it on SIGHUP, after external logrotate moved it (see ReopenOnSIGHUP)
+//	syslog: send to syslog daemon (RFC 5424, see SyslogWriter) at addr:port with network=udp (default) or network=tcp,
+//	        or at unix socket path (default /dev/log) with network=unix, syslog-facility is name or number (default "user")
+//	otlp: export to OpenTelemetry collector (see OTLPWriter) at addr:port with protocol=grpc (default),
+//	      or at url (e.g. "http://collector:4318/v1/logs") with protocol=http
+//	none: discard all messages
+//
+// Unknown options and options of other modes (e.g. path with gelf-tcp) are rejected.
+//
+// Common options:
+//
+//	level: maximum level sent (name or syslog severity number), default "debug"
+//	facility: GELF facility, default is the logger name
+//	hostname: reported host name, default os.Hostname()
+//	stderr: also print messages with this or lower level to stderr, "none" disables
+//	        (default "err", or "none" for modes writing to stdout)
+//	redact: "on" (default, DefaultRedactor), "hash" (DefaultRedactor hashing values) or "off"
+//	sample: "first/thereafter/interval", e.g. "100/10/1s": of the same messages in every second
+//	        send first 100, then every 10th (see Sampler), default is no sampling
+//	sample-level: most severe level sampled, default "err" (critical messages are never dropped)
+//	report-dsn: report panics and errors to Sentry-compatible endpoint (see ErrorReporter),
+//	            with report-level (default "err"), report-sample (fraction of reported messages, e.g. "0.1", default 1),
+//	            report-release, report-env and report-queue (directory of events waiting for the endpoint)
+//
+// name: locates config values by key "LOG_name"
+// globalConfig: config map, from where to read the string. If nil, environment variables are used
+func NewLoggerFromConfig(name string, globalConfig map[string]string) (Logger, error) {
+	key := fmt.Sprintf("LOG_%s", name)
+
+	mode, opts, err := ParseConfstr(key, globalConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	var config LoggerConfig
+	if err := decodeLoggerConfig(opts, &config); err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("Invalid config for logger, key: '%s' (failed to parse options struct)", key))
+	}
+
+	if config.Facility == "" {
+		config.Facility = name
+	}
+
+	if config.Hostname == "" {
+		if config.Hostname, err = os.Hostname(); err != nil {
+			return nil, errors.Wrap(err, "os.Hostname")
+		}
+	}
+
+	// Options are checked before the sink is created, so that nothing is left open on invalid config
+	if err := checkLoggerModeOptions(mode, opts); err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("Invalid config for logger, key: '%s'", key))
+	}
+
+	level := LevelDebug
+	if config.Level != "" {
+		if level, err = ParseLevel(config.Level); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("Invalid config for logger, key: '%s'", key))
+		}
+	}
+
+	redactor := DefaultRedactor
+	switch config.Redact {
+	case "", "on":
+	case "hash":
+		hashing := *DefaultRedactor
+		hashing.Hash = true
+		redactor = &hashing
+	case "off":
+		redactor = nil
+	default:
+		return nil, errors.New(fmt.Sprintf("Invalid config for logger, key: '%s' (invalid redact '%s')", key, config.Redact))
+	}
+
+	var sampler *Sampler
+	if config.Sample != "" {
+		if sampler, err = parseSamplerConfig(config.Sample, config.SampleLevel); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("Invalid config for logger, key: '%s'", key))
+		}
+	}
+
+	stderrLevel := Level(-1)
+	switch config.Stderr {
+	case "", "none":
+	default:
+		if stderrLevel, err = ParseLevel(config.Stderr); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("Invalid config for logger, key: '%s'", key))
+		}
+	}
+
+	writer, stderrDefault, err := newMessageWriterFromConfig(mode, config)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("Invalid config for logger, key: '%s'", key))
+	}
+
+	logger := newGelfLogger(writer, config.Facility, config.Hostname)
+	logger.level = level
+	logger.redactor = redactor
+	logger.SetSampler(sampler)
+
+	logger.stderrLevel = stderrLevel
+	if config.Stderr == "" {
+		logger.stderrLevel = stderrDefault
+	}
+
+	if config.ReportDSN != "" {
+		reporter, err := newErrorReporterFromConfig(config)
+		if err != nil {
+			writer.Close()
+			return nil, errors.Wrap(err, fmt.Sprintf("Invalid config for logger, key: '%s'", key))
+		}
+		logger.SetErrorReporter(reporter)
+	}
+
+	return logger, nil
+}
+
+// decodeLoggerConfig decodes options, unknown ones are an error (e.g. misspelled "sampel=...")
+func decodeLoggerConfig(opts map[string]string, config *LoggerConfig) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Result:      config,
+		ErrorUnused: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	return decoder.Decode(opts)
+}
+
+// checkLoggerModeOptions rejects options of other modes (e.g. "gelf-tcp;path=..."), which would be ignored
+func checkLoggerModeOptions(mode string, opts map[string]string) error {
+	modeOptions, ok := loggerModeOptions[mode]
+	if !ok {
+		return errors.Errorf("invalid mode '%s'", mode)
+	}
+
+	for k := range opts {
+		if !slices.Contains(modeOptions, k) && !slices.Contains(loggerCommonOptions, k) {
+			return errors.Errorf("option '%s' is not used in mode '%s'", k, mode)
+		}
+	}
+
+	return nil
+}
+
+func newMessageWriterFromConfig(mode string, config LoggerConfig) (MessageWriter, Level, error) {
+	switch mode {
+
+	case "gelf-tcp":
+
+		w, err := gelf.NewTCPWriter(config.GetAddress())
+		if err != nil {
+			return nil, 0, errors.Wrap(err, "gelf.NewTCPWriter")
+		}
+		w.Facility = config.Facility
+
+		log.Printf("Full logging to graylog @%s (tcp)", config.GetAddress())
+
+		return w, LevelError, nil
+
+	case "gelf-udp":
+
+		w, err := gelf.NewUDPWriter(config.GetAddress())
+		if err != nil {
+			return nil, 0, errors.Wrap(err, "gelf.NewUDPWriter")
+		}
+		w.Facility = config.Facility
+
+		log.Printf("Full logging to graylog @%s (udp)", config.GetAddress())
+
+		return w, LevelError, nil
+
+	case "json":
+
+		return NewJSONWriter(os.Stdout), -1, nil
+
+	case "console":
+
+		return NewConsoleWriter(os.Stdout, IsTerminal(os.Stdout)), -1, nil
+
+	case "file":
+
+		fileConfig := FileWriterConfig{
+			Path:     config.Path,
+			Format:   config.Format,
+			Compress: config.Compress != "0",
+		}
+
+		var err error
+
+		if config.MaxSize != "" {
+			if fileConfig.MaxSize, err = parseByteSize(config.MaxSize); err != nil {
+				return nil, 0, err
+			}
+		}
+
+		if config.MaxAge != "" {
+			if fileConfig.MaxAge, err = time.ParseDuration(config.MaxAge); err != nil {
+				return nil, 0, errors.Errorf("Invalid max-age '%s'", config.MaxAge)
+			}
+		}
+
+		if config.MaxBackups != "" {
+			if fileConfig.MaxBackups, err = strconv.Atoi(config.MaxBackups); err != nil {
+				return nil, 0, errors.Errorf("Invalid max-backups '%s'", config.MaxBackups)
+			}
+		}
+
+		if config.Reopen != "" && config.Reopen != "sighup" {
+			return nil, 0, errors.Errorf("Invalid reopen '%s' (expected sighup)", config.Reopen)
+		}
+
+		w, err := NewFileWriter(fileConfig)
+		if err != nil {
+			return nil, 0, errors.Wrap(err, "NewFileWriter")
+		}
+
+		if config.Reopen == "sighup" {
+			// Stopped by w.Close
+			var ctx context.Context
+			ctx, w.stopReopen = context.WithCancel(context.Background())
+			ReopenOnSIGHUP(ctx, w)
+		}
+
+		return w, LevelError, nil
+
+	case "syslog":
+
+		network := config.Network
+		if network == "" {
+			network = "udp"
+		}
+
+		facility := SyslogFacilityUser
+		if config.SyslogFacility != "" {
+			v, err := ParseSyslogFacility(config.SyslogFacility)
+			if err != nil {
+				return nil, 0, err
+			}
+			facility = v
+		}
+
+		addr := config.GetAddress()
+		if strings.HasPrefix(network, "unix") {
+			addr = config.Path
+			if addr == "" {
+				addr = "/dev/log"
+			}
+		}
+
+		w, err := NewSyslogWriter(network, addr, facility)
+		if err != nil {
+			return nil, 0, errors.Wrap(err, "NewSyslogWriter")
+		}
+
+		return w, LevelError, nil
+
+	case "otlp":
+
+		endpoint := config.GetAddress()
+		if config.Protocol == "http" {
+			endpoint = config.URL
+		}
+
+		w, err := NewOTLPWriter(OTLPConfig{
+			Endpoint:    endpoint,
+			Protocol:    config.Protocol,
+			ServiceName: config.Facility,
+		})
+		if err != nil {
+			return nil, 0, errors.Wrap(err, "NewOTLPWriter")
+		}
+
+		return w, LevelError, nil
+
+	case "none":
+
+		return NopWriter{}, -1, nil
+
+	default:
+		return nil, 0, errors.Errorf("invalid mode '%s'", mode)
+	}
+}
+
+func parseSamplerConfig(sample, sampleLevel string) (*Sampler, error) {
+	parts := strings.Split(sample, "/")
+	if len(parts) != 3 {
+		return nil, errors.Errorf("Invalid sample '%s' (expected first/thereafter/interval)", sample)
+	}
+
+	first, err1 := strconv.Atoi(parts[0])
+	thereafter, err2 := strconv.Atoi(parts[1])
+	interval, err3 := time.ParseDuration(parts[2])
+	if err1 != nil || err2 != nil || err3 != nil || first < 0 || thereafter < 0 || interval <= 0 {
+		return nil, errors.Errorf("Invalid sample '%s' (expected first/thereafter/interval)", sample)
+	}
+
+	minLevel := LevelError
+	if sampleLevel != "" {
+		var err error
+		if minLevel, err = ParseLevel(sampleLevel); err != nil {
+			return nil, err
+		}
+	}
+
+	return NewSampler(interval, minLevel, SamplingPolicy{First: first, Thereafter: thereafter}), nil
+}
+
+func newErrorReporterFromConfig(config LoggerConfig) (*ErrorReporter, error) {
+	reporterConfig := ErrorReporterConfig{
+		DSN:         config.ReportDSN,
+		Release:     config.ReportRelease,
+		Environment: config.ReportEnv,
+		QueueDir:    config.ReportQueue,
+	}
+
+	if config.ReportLevel != "" {
+		level, err := ParseLevel(config.ReportLevel)
+		if err != nil {
+			return nil, err
+		}
+		reporterConfig.Level = &level
+	}
+
+	if config.ReportSample != "" {
+		rate, err := strconv.ParseFloat(config.ReportSample, 64)
+		if err != nil {
+			return nil, errors.Errorf("Invalid report-sample '%s'", config.ReportSample)
+		}
+		reporterConfig.SampleRate = &rate
+	}
+
+	return NewErrorReporter(reporterConfig)
+}
+
+// parseByteSize parses size in bytes with optional K, M or G suffix
+func parseByteSize(s string) (int64, error) {
+	multiplier := int64(1)
+
+	switch strings.ToUpper(s[len(s)-1:]) {
+	case "K":
+		multiplier = 1 << 10
+	case "M":
+		multiplier = 1 << 20
+	case "G":
+		multiplier = 1 << 30
+	}
+
+	if multiplier != 1 {
+		s = s[:len(s)-1]
+	}
+
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || v < 0 {
+		return 0, errors.Errorf("Invalid size '%s'", s)
+	}
+
+	return v * multiplier, nil
+}
+
+// NopWriter discards all messages
+type NopWriter struct{}
+
+func (NopWriter) WriteMessage(*gelf.Message) error {
+	return nil
+}
+
+func (NopWriter) Close() error {
+	return nil
+}
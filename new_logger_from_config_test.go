@@ -0,0 +1,79 @@
+package gobase
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewLoggerFromConfigModes(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := map[string]string{
+		"none":                              "gobase.NopWriter",
+		"json;stderr=err":                   "*gobase.JSONWriter",
+		"console;level=info":                "*gobase.ConsoleWriter",
+		"file;path=" + dir + "/app.log":     "*gobase.FileWriter",
+		"otlp;addr=127.0.0.1;port=1":        "*gobase.OTLPWriter",
+		"none;sample=100/10/1s;redact=hash": "gobase.NopWriter",
+	}
+
+	for config, writerType := range tests {
+		logger, err := NewLoggerFromConfig("test", map[string]string{"LOG_test": config})
+		if err != nil {
+			t.Errorf("%s: %v", config, err)
+			continue
+		}
+
+		if got := logger.(*GelfLogger).stats.sink; got != writerType {
+			t.Errorf("%s: writer %s, want %s", config, got, writerType)
+		}
+		logger.Close()
+	}
+}
+
+func TestNewLoggerFromConfigOptions(t *testing.T) {
+	logger, err := NewLoggerFromConfig("bot", map[string]string{"LOG_bot": "none;level=warning;stderr=crit;redact=off"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logger.Close()
+
+	gl := logger.(*GelfLogger)
+	if gl.level != LevelWarning || gl.stderrLevel != LevelCritical || gl.redactor != nil || gl.facility != "bot" {
+		t.Errorf("options not applied: level %s, stderr %s, redactor %v, facility %s", gl.level, gl.stderrLevel, gl.redactor, gl.facility)
+	}
+}
+
+func TestNewLoggerFromConfigInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+
+	tests := map[string]string{
+		"loud":                                        "invalid mode 'loud'",
+		"none;level=loud":                             "loud",
+		"none;sampel=1/1/1s":                          "sampel",
+		"none;stderr=no":                              "no",
+		"none;redact=maybe":                           "invalid redact",
+		"none;sample=100/10":                          "Invalid sample",
+		"none;sample=a/10/1s":                         "Invalid sample",
+		"none;sample=100/10/0s":                       "Invalid sample",
+		"none;sample=-1/10/1s":                        "Invalid sample",
+		"none;sample=1/1/1s;sample-level=x":           "x",
+		"gelf-tcp;addr=127.0.0.1;port=1;path=" + path: "option 'path' is not used in mode 'gelf-tcp'",
+		"json;addr=127.0.0.1":                         "option 'addr' is not used in mode 'json'",
+		"file;path=" + path + ";url=http://x":         "option 'url' is not used in mode 'file'",
+		"file;path=" + path + ";max-size=big":         "Invalid size",
+	}
+
+	for config, want := range tests {
+		logger, err := NewLoggerFromConfig("test", map[string]string{"LOG_test": config})
+		if err == nil {
+			t.Errorf("%s: invalid config accepted", config)
+			logger.Close()
+			continue
+		}
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: error %q does not mention %q", config, err, want)
+		}
+	}
+}
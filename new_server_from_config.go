@@ -17,8 +17,10 @@ import (
 )
 
 type ServerConfig struct {
-	Addr string `mapstructure:"addr"`
-	Port string `mapstructure:"port"`
+	Addr     string `mapstructure:"addr"`
+	Port     string `mapstructure:"port"`
+	Logging  string `mapstructure:"logging"`
+	Recovery string `mapstructure:"recovery"`
 }
 
 type ServerTLS struct {
@@ -47,6 +49,10 @@ func (s ServerConfig) GetBindAddress() string {
 //
 //	"test;key1=value;key2=other value;"
 //
+// Logging interceptors (request ID, method, peer, status code, duration; see LoggingUnaryServerInterceptor)
+// are installed with the default logger, unless "logging=off" is set.
+// Panic recovery interceptors (see RecoveryUnaryServerInterceptor) are installed unless "recovery=off" is set.
+//
 // serverName: locates config values by key "GRPC_SERVER_serviceName
 // globalConfig: config map, from where to read the string. If nil, environment variables are used
 // opts: other gRPC server options to use
@@ -59,6 +65,21 @@ func NewServerFromConfig(serverName string, globalConfig map[string]string, opts
 		return nil, nil, err
 	}
 
+	// Recovery is inner to logging, so that a call recovered from panic is logged with codes.Internal
+	if config.Recovery != "off" {
+		opts = append([]grpc.ServerOption{
+			grpc.ChainUnaryInterceptor(RecoveryUnaryServerInterceptor(nil)),
+			grpc.ChainStreamInterceptor(RecoveryStreamServerInterceptor(nil)),
+		}, opts...)
+	}
+
+	if config.Logging != "off" {
+		opts = append([]grpc.ServerOption{
+			grpc.ChainUnaryInterceptor(LoggingUnaryServerInterceptor(nil)),
+			grpc.ChainStreamInterceptor(LoggingStreamServerInterceptor(nil)),
+		}, opts...)
+	}
+
 	server := grpc.NewServer(append(opts, securityOption)...)
 
 	listener, err := net.Listen("tcp", config.GetBindAddress())
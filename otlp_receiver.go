@@ -0,0 +1,115 @@
+package gobase
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// OTLPReceiver is in-process stand-in for OpenTelemetry collector, receiving logs over gRPC and HTTP (protobuf).
+// Use it in tests of OTLPWriter.
+type OTLPReceiver struct {
+	collogspb.UnimplementedLogsServiceServer
+
+	mu        sync.Mutex
+	records   []*logspb.LogRecord
+	resources []*resourcepb.Resource
+	server    *grpc.Server
+}
+
+func NewOTLPReceiver() *OTLPReceiver {
+	return &OTLPReceiver{}
+}
+
+// ListenGRPC starts gRPC server, addr "127.0.0.1:0" picks a free port. Returns listening address.
+func (r *OTLPReceiver) ListenGRPC(addr string) (string, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", err
+	}
+
+	r.server = grpc.NewServer()
+	collogspb.RegisterLogsServiceServer(r.server, r)
+
+	go r.server.Serve(listener)
+
+	return listener.Addr().String(), nil
+}
+
+// Close stops gRPC server
+func (r *OTLPReceiver) Close() {
+	if r.server != nil {
+		r.server.Stop()
+	}
+}
+
+func (r *OTLPReceiver) Export(_ context.Context, req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, rl := range req.GetResourceLogs() {
+		for _, sl := range rl.GetScopeLogs() {
+			for _, record := range sl.GetLogRecords() {
+				r.records = append(r.records, record)
+				r.resources = append(r.resources, rl.GetResource())
+			}
+		}
+	}
+
+	return &collogspb.ExportLogsServiceResponse{}, nil
+}
+
+// ServeHTTP handles OTLP/HTTP export requests (protobuf encoding), mount it at /v1/logs
+func (r *OTLPReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var exportReq collogspb.ExportLogsServiceRequest
+	if err := proto.Unmarshal(body, &exportReq); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, _ := r.Export(req.Context(), &exportReq)
+
+	data, err := proto.Marshal(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.Write(data)
+}
+
+// Records returns received log records
+func (r *OTLPReceiver) Records() []*logspb.LogRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]*logspb.LogRecord(nil), r.records...)
+}
+
+// Resources returns resource of every received record (same order as Records)
+func (r *OTLPReceiver) Resources() []*resourcepb.Resource {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]*resourcepb.Resource(nil), r.resources...)
+}
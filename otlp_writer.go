@@ -0,0 +1,384 @@
+package gobase
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-faster/errors"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/Graylog2/go-gelf.v2/gelf"
+)
+
+type OTLPConfig struct {
+	// Endpoint is "host:port" for gRPC, or URL (e.g. "http://collector:4318/v1/logs") for HTTP
+	Endpoint string
+	// Protocol is "grpc" (default) or "http" (protobuf encoding)
+	Protocol string
+	// ServiceName is "service.name" resource attribute, GELF facility of the first message if empty
+	ServiceName string
+	// Headers are sent with every export request (gRPC metadata or HTTP headers), e.g. authorization
+	Headers map[string]string
+	// BatchSize is max number of records per export request, default 512
+	BatchSize int
+	// FlushInterval is max time a record waits in a batch, default 1s
+	FlushInterval time.Duration
+	// QueueSize is max number of records waiting for export, new records are dropped when full, default 4096
+	QueueSize int
+	// Timeout of export request, default 10s
+	Timeout time.Duration
+	// DialOptions for gRPC connection, insecure transport if empty
+	DialOptions []grpc.DialOption
+}
+
+// OTLPWriter exports messages as OpenTelemetry log records, in batches, over gRPC or HTTP.
+// Severity is mapped from syslog level, kind and fields are attributes,
+// "trace_id" and "span_id" fields (hex) are set as record trace context.
+type OTLPWriter struct {
+	config   OTLPConfig
+	resource *resourcepb.Resource
+	once     sync.Once
+	client   collogspb.LogsServiceClient
+	conn     *grpc.ClientConn
+	httpc    *http.Client
+	queue    chan *logspb.LogRecord
+	flush    chan chan struct{}
+	done     chan struct{}
+	closed   sync.WaitGroup
+	stats    atomic.Pointer[sinkStats] // of the logger, export results are counted in its stats
+
+	mu      sync.RWMutex // guards stopped against WriteMessage, so no record is queued after Close drains the queue
+	stopped bool
+}
+
+func NewOTLPWriter(config OTLPConfig) (*OTLPWriter, error) {
+	if config.Endpoint == "" {
+		return nil, errors.New("No endpoint specified for OTLP writer")
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 512
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = time.Second
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = 4096
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+
+	w := &OTLPWriter{
+		config: config,
+		queue:  make(chan *logspb.LogRecord, config.QueueSize),
+		flush:  make(chan chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	switch config.Protocol {
+	case "", "grpc":
+		opts := config.DialOptions
+		if len(opts) == 0 {
+			opts = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+		}
+
+		conn, err := grpc.NewClient(config.Endpoint, opts...)
+		if err != nil {
+			return nil, errors.Wrap(err, "grpc.NewClient")
+		}
+
+		w.conn = conn
+		w.client = collogspb.NewLogsServiceClient(conn)
+	case "http":
+		w.httpc = &http.Client{Timeout: config.Timeout}
+	default:
+		return nil, errors.Errorf("Invalid OTLP protocol '%s'", config.Protocol)
+	}
+
+	w.closed.Add(1)
+	go w.run()
+
+	return w, nil
+}
+
+func (w *OTLPWriter) setSinkStats(s *sinkStats) {
+	w.stats.Store(s)
+}
+
+func (w *OTLPWriter) WriteMessage(m *gelf.Message) error {
+	w.once.Do(func() {
+		w.resource = w.newResource(m)
+	})
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.stopped {
+		return errors.New("OTLP writer is closed")
+	}
+
+	select {
+	case w.queue <- otlpLogRecord(m):
+		return nil
+	default:
+		return errors.Wrap(ErrMessageDropped, "OTLP export queue is full")
+	}
+}
+
+// Flush exports all queued records
+func (w *OTLPWriter) Flush() {
+	c := make(chan struct{})
+	select {
+	case w.flush <- c:
+		<-c
+	case <-w.done:
+	}
+}
+
+// Close exports queued records and closes connection
+func (w *OTLPWriter) Close() error {
+	w.mu.Lock()
+	if w.stopped {
+		w.mu.Unlock()
+		return nil
+	}
+	w.stopped = true
+	close(w.done)
+	w.mu.Unlock()
+
+	w.closed.Wait()
+
+	if w.conn != nil {
+		return w.conn.Close()
+	}
+	return nil
+}
+
+func (w *OTLPWriter) run() {
+	defer w.closed.Done()
+
+	ticker := time.NewTicker(w.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*logspb.LogRecord, 0, w.config.BatchSize)
+
+	export := func() {
+		if len(batch) == 0 {
+			return
+		}
+		size, err := w.export(batch)
+		if err != nil {
+			// Logging it through a logger could loop back here
+			fmt.Fprintf(os.Stderr, "OTLPWriter: export of %d records failed: %s\n", len(batch), err)
+		}
+		if stats := w.stats.Load(); stats != nil {
+			stats.exported(len(batch), size, err)
+		}
+		batch = make([]*logspb.LogRecord, 0, w.config.BatchSize)
+	}
+
+	drain := func() {
+		for {
+			select {
+			case r := <-w.queue:
+				batch = append(batch, r)
+				if len(batch) >= w.config.BatchSize {
+					export()
+				}
+			default:
+				export()
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case r := <-w.queue:
+			batch = append(batch, r)
+			if len(batch) >= w.config.BatchSize {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		case c := <-w.flush:
+			drain()
+			close(c)
+		case <-w.done:
+			drain()
+			return
+		}
+	}
+}
+
+// export sends records, returns size of the request
+func (w *OTLPWriter) export(records []*logspb.LogRecord) (int, error) {
+	req := &collogspb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{
+			Resource: w.resource,
+			ScopeLogs: []*logspb.ScopeLogs{{
+				Scope:      &commonpb.InstrumentationScope{Name: "github.com/flogram-lab/gobase"},
+				LogRecords: records,
+			}},
+		}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), w.config.Timeout)
+	defer cancel()
+
+	if w.client != nil {
+		for k, v := range w.config.Headers {
+			ctx = metadata.AppendToOutgoingContext(ctx, k, v)
+		}
+		_, err := w.client.Export(ctx, req)
+		return proto.Size(req), err
+	}
+
+	body, err := proto.Marshal(req)
+	if err != nil {
+		return 0, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, w.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	httpReq.Header.Set("Content-Type", "application/x-protobuf")
+	for k, v := range w.config.Headers {
+		httpReq.Header.Set(k, v)
+	}
+
+	resp, err := w.httpc.Do(httpReq)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		return 0, errors.Errorf("HTTP status %s", resp.Status)
+	}
+
+	return len(body), nil
+}
+
+func (w *OTLPWriter) newResource(m *gelf.Message) *resourcepb.Resource {
+	serviceName := w.config.ServiceName
+	if serviceName == "" {
+		serviceName = m.Facility
+	}
+
+	return &resourcepb.Resource{
+		Attributes: []*commonpb.KeyValue{
+			{Key: "service.name", Value: otlpValue(serviceName)},
+			{Key: "host.name", Value: otlpValue(m.Host)},
+		},
+	}
+}
+
+// OTLPSeverity maps syslog level to OpenTelemetry severity number
+func OTLPSeverity(level Level) logspb.SeverityNumber {
+	switch level {
+	case LevelEmergency:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_FATAL
+	case LevelAlert:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_ERROR3
+	case LevelCritical:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_ERROR2
+	case LevelError:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_ERROR
+	case LevelWarning:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_WARN
+	case LevelNotice:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_INFO2
+	case LevelInfo:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_INFO
+	case LevelDebug:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG
+	default:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED
+	}
+}
+
+func otlpLogRecord(m *gelf.Message) *logspb.LogRecord {
+	ts := uint64(m.TimeUnix * float64(time.Second))
+
+	r := &logspb.LogRecord{
+		TimeUnixNano:         ts,
+		ObservedTimeUnixNano: uint64(time.Now().UnixNano()),
+		SeverityNumber:       OTLPSeverity(Level(m.Level)),
+		SeverityText:         Level(m.Level).String(),
+		Body:                 otlpValue(m.Full),
+		Attributes:           []*commonpb.KeyValue{{Key: "kind", Value: otlpValue(m.Short)}},
+	}
+
+	for k, v := range m.Extra {
+		switch k {
+		case "trace_id":
+			if id, err := hex.DecodeString(fmt.Sprint(v)); err == nil && len(id) == 16 {
+				r.TraceId = id
+				continue
+			}
+		case "span_id":
+			if id, err := hex.DecodeString(fmt.Sprint(v)); err == nil && len(id) == 8 {
+				r.SpanId = id
+				continue
+			}
+		}
+
+		r.Attributes = append(r.Attributes, &commonpb.KeyValue{Key: k, Value: otlpValue(v)})
+	}
+
+	return r
+}
+
+func otlpValue(v any) *commonpb.AnyValue {
+	switch v := v.(type) {
+	case string:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}}
+	case bool:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v}}
+	case int:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(v)}}
+	case int32:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(v)}}
+	case int64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: v}}
+	case uint32:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(v)}}
+	case float32:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: float64(v)}}
+	case float64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: v}}
+	case []byte:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BytesValue{BytesValue: v}}
+	case map[string]any:
+		kvs := make([]*commonpb.KeyValue, 0, len(v))
+		for k, item := range v {
+			kvs = append(kvs, &commonpb.KeyValue{Key: k, Value: otlpValue(item)})
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{Values: kvs}}}
+	case []any:
+		values := make([]*commonpb.AnyValue, len(v))
+		for i := range v {
+			values[i] = otlpValue(v[i])
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{Values: values}}}
+	default:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: fmt.Sprint(v)}}
+	}
+}
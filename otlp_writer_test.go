@@ -0,0 +1,142 @@
+package gobase
+
+import (
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"gopkg.in/Graylog2/go-gelf.v2/gelf"
+)
+
+func otlpAttribute(r *logspb.LogRecord, key string) (string, bool) {
+	for _, kv := range r.GetAttributes() {
+		if kv.GetKey() == key {
+			return kv.GetValue().GetStringValue(), true
+		}
+	}
+	return "", false
+}
+
+func testOTLPExport(t *testing.T, receiver *OTLPReceiver, config OTLPConfig) {
+	t.Helper()
+
+	w, err := NewOTLPWriter(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := newGelfLogger(w, "bot", "host1").AddRequestID("req1")
+	logger.Error("db", "query failed", "trace_id", "4bf92f3577b34da6a3ce929d0e0e4736", "span_id", "00f067aa0ba902b7")
+	logger.Info("db", "connected")
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	records := receiver.Records()
+	if len(records) != 2 {
+		t.Fatalf("received %d records, want 2", len(records))
+	}
+
+	r := records[0]
+	if r.GetSeverityNumber() != logspb.SeverityNumber_SEVERITY_NUMBER_ERROR || r.GetBody().GetStringValue() != "query failed" {
+		t.Errorf("unexpected record %v", r)
+	}
+	if kind, _ := otlpAttribute(r, "kind"); kind != "db" {
+		t.Errorf("kind attribute %q", kind)
+	}
+	if ruid, _ := otlpAttribute(r, "request_uid"); ruid != "req1" {
+		t.Errorf("request_uid attribute %q", ruid)
+	}
+	if hex.EncodeToString(r.GetTraceId()) != "4bf92f3577b34da6a3ce929d0e0e4736" || hex.EncodeToString(r.GetSpanId()) != "00f067aa0ba902b7" {
+		t.Errorf("trace context not set: %x %x", r.GetTraceId(), r.GetSpanId())
+	}
+	if _, ok := otlpAttribute(r, "trace_id"); ok {
+		t.Errorf("trace_id kept as attribute")
+	}
+
+	if name := receiver.Resources()[0].GetAttributes()[0]; name.GetKey() != "service.name" || name.GetValue().GetStringValue() != "bot" {
+		t.Errorf("unexpected resource attribute %v", name)
+	}
+
+	if records[1].GetSeverityNumber() != logspb.SeverityNumber_SEVERITY_NUMBER_INFO {
+		t.Errorf("unexpected severity %v", records[1].GetSeverityNumber())
+	}
+}
+
+func TestOTLPWriterGRPC(t *testing.T) {
+	receiver := NewOTLPReceiver()
+	addr, err := receiver.ListenGRPC("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Close()
+
+	testOTLPExport(t, receiver, OTLPConfig{Endpoint: addr, BatchSize: 1})
+}
+
+func TestOTLPWriterHTTP(t *testing.T) {
+	receiver := NewOTLPReceiver()
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	testOTLPExport(t, receiver, OTLPConfig{Endpoint: server.URL + "/v1/logs", Protocol: "http"})
+}
+
+func TestOTLPWriterBytesInStats(t *testing.T) {
+	receiver := NewOTLPReceiver()
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	w, err := NewOTLPWriter(OTLPConfig{Endpoint: server.URL + "/v1/logs", Protocol: "http"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	logger := newGelfLogger(w, "bot", "host1")
+	logger.Info("db", "connected")
+	w.Flush()
+
+	if stats := logger.Stats(); stats.Sent != 1 || stats.Bytes == 0 {
+		t.Errorf("export size not in stats: %+v", stats)
+	}
+}
+
+func TestOTLPWriterClosed(t *testing.T) {
+	w, err := NewOTLPWriter(OTLPConfig{Endpoint: "127.0.0.1:1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	if err := w.WriteMessage(&gelf.Message{Short: "x"}); err == nil {
+		t.Error("WriteMessage after Close succeeded")
+	}
+}
+
+func TestOTLPWriterExportFailureInStats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	w, err := NewOTLPWriter(OTLPConfig{Endpoint: server.URL, Protocol: "http", BatchSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := newGelfLogger(w, "bot", "host1")
+	logger.SetFailureThreshold(0)
+	logger.Error("db", "query failed")
+	w.Flush()
+
+	stats := logger.Stats()
+	if stats.Failed != 1 || stats.Healthy || stats.LastError == "" {
+		t.Errorf("export failure not in stats: %+v", stats)
+	}
+
+	w.Close()
+}
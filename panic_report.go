@@ -0,0 +1,285 @@
+package gobase
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Goroutine dump in a log message is truncated to fit GELF message size limits, crash file keeps all of it
+const panicDumpFieldLimit = 32 * 1024
+
+var processStarted = time.Now()
+
+type PanicReportConfig struct {
+	// CrashDir: if set, every panic report is also written to a file in this directory
+	CrashDir string
+	// DumpGoroutines adds stacks of all goroutines to the report
+	DumpGoroutines bool
+}
+
+var panicReportConfig atomic.Pointer[PanicReportConfig]
+
+// ConfigurePanicReports sets options of reports made by LogPanic, LogPanicErr and LogPanicExit
+func ConfigurePanicReports(config PanicReportConfig) {
+	panicReportConfig.Store(&config)
+}
+
+// PanicFrame is a stack frame of panicking goroutine
+type PanicFrame struct {
+	Function string
+	File     string
+	Line     int
+}
+
+func (f PanicFrame) String() string {
+	return fmt.Sprintf("%s (%s:%d)", f.Function, f.File, f.Line)
+}
+
+// PanicReport describes recovered panic
+type PanicReport struct {
+	Time           time.Time
+	Kind           string
+	Title          string       // what was running: gRPC method, goroutine name, ...
+	Value          any          // value passed to panic()
+	Error          error        // value passed to panic(), if it is an error (runtime errors included)
+	Frames         []PanicFrame // stack of panicking goroutine, from the panic() call site up
+	Stack          string       // stack of panicking goroutine as printed by runtime
+	Goroutines     int
+	GoroutinesDump string // stacks of all goroutines, if enabled by ConfigurePanicReports
+	GoVersion      string
+	BuildPath      string // main module path
+	BuildVersion   string // main module version
+	VCSRevision    string
+	Uptime         time.Duration
+	RequestID      string // request ID of the logger
+	CrashFile      string // path of written crash file, if any
+	EventID        string // ID of error event, if the logger has ErrorReporter
+}
+
+// NewPanicReport describes panic value, must be called in deferred function during panicking to get its stack
+func NewPanicReport(value any, l Logger, kind, title string) *PanicReport {
+	report := &PanicReport{
+		Time:       time.Now(),
+		Kind:       kind,
+		Title:      title,
+		Value:      value,
+		Frames:     panicFrames(),
+		Stack:      string(debug.Stack()),
+		Goroutines: runtime.NumGoroutine(),
+		GoVersion:  runtime.Version(),
+		Uptime:     time.Since(processStarted),
+		RequestID:  RequestIDFrom(l),
+	}
+
+	report.Error, _ = value.(error)
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		report.BuildPath = info.Main.Path
+		report.BuildVersion = info.Main.Version
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" {
+				report.VCSRevision = s.Value
+			}
+		}
+	}
+
+	if config := panicReportConfig.Load(); config != nil && config.DumpGoroutines {
+		report.GoroutinesDump = goroutinesDump()
+	}
+
+	return report
+}
+
+// panicFrames returns frames of the goroutine starting at the function that panicked
+func panicFrames() []PanicFrame {
+	pc := make([]uintptr, 64)
+	pc = pc[:runtime.Callers(1, pc)]
+
+	var frames []PanicFrame
+	panicking := false
+
+	iter := runtime.CallersFrames(pc)
+	for {
+		frame, more := iter.Next()
+
+		switch {
+		case frame.Function == "runtime.gopanic":
+			// Frames above are the deferred recovery functions
+			panicking = true
+			frames = frames[:0]
+		case panicking && len(frames) == 0 && strings.HasPrefix(frame.Function, "runtime."):
+			// runtime.panicmem, runtime.sigpanic, ... of runtime errors
+		default:
+			frames = append(frames, PanicFrame{Function: frame.Function, File: frame.File, Line: frame.Line})
+		}
+
+		if !more {
+			break
+		}
+	}
+
+	return frames
+}
+
+func goroutinesDump() string {
+	buf := make([]byte, 1024*1024)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) || len(buf) >= 64*1024*1024 {
+			return string(buf[:n])
+		}
+		buf = make([]byte, len(buf)*2)
+	}
+}
+
+// Message returns short description for log message
+func (r *PanicReport) Message() string {
+	if r.Title != "" {
+		return fmt.Sprintf("panic in %s: %v", r.Title, r.Value)
+	}
+	return fmt.Sprintf("panic: %v", r.Value)
+}
+
+// Fields returns report as log message fields (with ErrorField fields if panic value is an error),
+// "file", "line" and "function" are location of the panic
+func (r *PanicReport) Fields() map[string]any {
+	fields := map[string]any{
+		"panic_value": fmt.Sprint(r.Value),
+		"panic_type":  fmt.Sprintf("%T", r.Value),
+		"stacktrace":  r.Stack,
+		"goroutines":  r.Goroutines,
+		"go_version":  r.GoVersion,
+		"uptime_s":    int64(r.Uptime.Seconds()),
+	}
+
+	if r.Error != nil {
+		for k, v := range ErrorField(r.Error) {
+			fields[k] = v
+		}
+	}
+
+	if len(r.Frames) > 0 {
+		lines := make([]string, len(r.Frames))
+		for i, f := range r.Frames {
+			lines[i] = f.String()
+		}
+		fields["panic_frames"] = strings.Join(lines, "\n")
+
+		// Caller fields point to where panic happened, not to the recovery code
+		fields["file"] = filepath.Base(filepath.Dir(r.Frames[0].File)) + "/" + filepath.Base(r.Frames[0].File)
+		fields["line"] = r.Frames[0].Line
+		fields["function"] = r.Frames[0].Function
+	}
+
+	if r.Title != "" {
+		fields["panic_title"] = r.Title
+	}
+	if r.BuildPath != "" {
+		fields["build_path"] = r.BuildPath
+		fields["build_version"] = r.BuildVersion
+	}
+	if r.VCSRevision != "" {
+		fields["vcs_revision"] = r.VCSRevision
+	}
+	if r.EventID != "" {
+		fields["event_id"] = r.EventID
+	}
+	if r.CrashFile != "" {
+		fields["crash_file"] = r.CrashFile
+	} else if r.GoroutinesDump != "" {
+		dump := r.GoroutinesDump
+		if len(dump) > panicDumpFieldLimit {
+			dump = dump[:panicDumpFieldLimit] + "\n... truncated"
+		}
+		fields["goroutines_dump"] = dump
+	}
+
+	return fields
+}
+
+// String formats report as text of crash file
+func (r *PanicReport) String() string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "%s\n\n", r.Message())
+	fmt.Fprintf(&sb, "time:        %s\n", r.Time.Format(time.RFC3339Nano))
+	fmt.Fprintf(&sb, "kind:        %s\n", r.Kind)
+	fmt.Fprintf(&sb, "value type:  %T\n", r.Value)
+	if r.RequestID != "" {
+		fmt.Fprintf(&sb, "request id:  %s\n", r.RequestID)
+	}
+	if r.EventID != "" {
+		fmt.Fprintf(&sb, "event id:    %s\n", r.EventID)
+	}
+	fmt.Fprintf(&sb, "goroutines:  %d\n", r.Goroutines)
+	fmt.Fprintf(&sb, "uptime:      %s\n", r.Uptime.Round(time.Second))
+	fmt.Fprintf(&sb, "go version:  %s\n", r.GoVersion)
+	if r.BuildPath != "" {
+		fmt.Fprintf(&sb, "build:       %s %s %s\n", r.BuildPath, r.BuildVersion, r.VCSRevision)
+	}
+	if r.Error != nil {
+		fmt.Fprintf(&sb, "\nerror:\n%+v\n", r.Error)
+	}
+
+	sb.WriteString("\nframes:\n")
+	for _, f := range r.Frames {
+		fmt.Fprintf(&sb, "  %s\n", f)
+	}
+
+	fmt.Fprintf(&sb, "\nstack:\n%s\n", r.Stack)
+
+	if r.GoroutinesDump != "" {
+		fmt.Fprintf(&sb, "\nall goroutines:\n%s\n", r.GoroutinesDump)
+	}
+
+	return sb.String()
+}
+
+// writeCrashFile writes report to configured crash directory, if any
+func (r *PanicReport) writeCrashFile() error {
+	config := panicReportConfig.Load()
+	if config == nil || config.CrashDir == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(config.CrashDir, 0755); err != nil {
+		return err
+	}
+
+	path := filepath.Join(config.CrashDir, fmt.Sprintf("crash-%s-%d.txt", r.Time.Format("2006-01-02T15-04-05.000"), os.Getpid()))
+	if err := os.WriteFile(path, []byte(r.String()), 0644); err != nil {
+		return err
+	}
+
+	r.CrashFile = path
+	return nil
+}
+
+// reportPanic makes panic report, sends it to ErrorReporter of the logger and writes crash file if configured,
+// prints it to stderr and logs it at LevelCritical
+func reportPanic(l Logger, kind, title string, value any) *PanicReport {
+	report := NewPanicReport(value, l, kind, title)
+
+	if err := report.writeCrashFile(); err != nil {
+		stderrLog.Println("ERROR write crash file:", err)
+	}
+
+	if r := errorReporterOf(l); r.reporter != nil {
+		report.EventID = r.reporter.ReportPanic(report, r.facility, r.hostname, r.redactor)
+	}
+
+	// Stderr has the report even if the logger is what panicked
+	stderrLog.Printf("%s\n%s", report.Message(), report.Stack)
+
+	if l != nil {
+		l.Message(LevelCritical, kind, report.Message(), report.Fields())
+	}
+
+	return report
+}
@@ -3,6 +3,7 @@ package gobase
 import (
 	"math/rand"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -15,9 +16,16 @@ const (
 
 var src = rand.NewSource(time.Now().UnixNano())
 
+// rand.Source is not safe for concurrent use (request IDs are generated by concurrent gRPC handlers)
+var srcMu sync.Mutex
+
 func RandStringBytesMaskImprSrcSB(n int) string {
 	sb := strings.Builder{}
 	sb.Grow(n)
+
+	srcMu.Lock()
+	defer srcMu.Unlock()
+
 	// A src.Int63() generates 63 random bits, enough for letterIdxMax characters!
 	for i, cache, remain := n-1, src.Int63(), letterIdxMax; i >= 0; {
 		if remain == 0 {
@@ -0,0 +1,214 @@
+package gobase
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/Graylog2/go-gelf.v2/gelf"
+)
+
+// RecordedMessage is a message captured by RecordingLogger
+type RecordedMessage struct {
+	Time      time.Time
+	Level     Level
+	Kind      string
+	Message   string
+	Fields    map[string]any // merged logger and message fields, including request_uid and caller
+	RequestID string
+}
+
+func (m RecordedMessage) String() string {
+	return fmt.Sprintf("%s %s: %s %v", m.Level, m.Kind, m.Message, m.Fields)
+}
+
+// RecordingWriter keeps all messages in memory
+type RecordingWriter struct {
+	mu       sync.Mutex
+	messages []RecordedMessage
+}
+
+func (w *RecordingWriter) WriteMessage(m *gelf.Message) error {
+	ruid, _ := m.Extra["request_uid"].(string)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.messages = append(w.messages, RecordedMessage{
+		Time:      time.Unix(0, int64(m.TimeUnix*float64(time.Second))),
+		Level:     Level(m.Level),
+		Kind:      m.Short,
+		Message:   m.Full,
+		Fields:    m.Extra,
+		RequestID: ruid,
+	})
+
+	return nil
+}
+
+func (w *RecordingWriter) Close() error {
+	return nil
+}
+
+// RecordingLogger captures every message in memory, for tests of code that logs.
+// Loggers made by AddRequestID record to the same RecordingLogger.
+type RecordingLogger struct {
+	*GelfLogger
+	recorder *RecordingWriter
+}
+
+func NewRecordingLogger() *RecordingLogger {
+	recorder := &RecordingWriter{}
+
+	return &RecordingLogger{
+		GelfLogger: newGelfLogger(recorder, "test", "test"),
+		recorder:   recorder,
+	}
+}
+
+// Messages returns copy of recorded messages
+func (l *RecordingLogger) Messages() []RecordedMessage {
+	l.recorder.mu.Lock()
+	defer l.recorder.mu.Unlock()
+
+	return append([]RecordedMessage(nil), l.recorder.messages...)
+}
+
+// Reset forgets recorded messages
+func (l *RecordingLogger) Reset() {
+	l.recorder.mu.Lock()
+	defer l.recorder.mu.Unlock()
+
+	l.recorder.messages = nil
+}
+
+// FieldMatcher checks fields of a recorded message
+type FieldMatcher struct {
+	Description string
+	Match       func(fields map[string]any) bool
+}
+
+// HasField matches messages having field key
+func HasField(key string) FieldMatcher {
+	return FieldMatcher{
+		Description: fmt.Sprintf("has field %s", key),
+		Match: func(fields map[string]any) bool {
+			_, ok := fields[key]
+			return ok
+		},
+	}
+}
+
+// FieldEquals matches messages having field key with value (compared as printed with %v, so 1 equals int64(1))
+func FieldEquals(key string, value any) FieldMatcher {
+	return FieldMatcher{
+		Description: fmt.Sprintf("%s=%v", key, value),
+		Match: func(fields map[string]any) bool {
+			v, ok := fields[key]
+			return ok && fmt.Sprint(v) == fmt.Sprint(value)
+		},
+	}
+}
+
+// FieldContains matches messages having field key with value containing substring (as printed with %v)
+func FieldContains(key string, substring string) FieldMatcher {
+	return FieldMatcher{
+		Description: fmt.Sprintf("%s contains %q", key, substring),
+		Match: func(fields map[string]any) bool {
+			v, ok := fields[key]
+			return ok && strings.Contains(fmt.Sprint(v), substring)
+		},
+	}
+}
+
+// Find returns recorded messages of level and kind (empty kind matches any), with text containing substring and matching all matchers
+func (l *RecordingLogger) Find(level Level, kind, substring string, matchers ...FieldMatcher) []RecordedMessage {
+	var found []RecordedMessage
+
+	for _, m := range l.Messages() {
+		if m.Level != level || (kind != "" && m.Kind != kind) || !strings.Contains(m.Message, substring) {
+			continue
+		}
+
+		matched := true
+		for _, matcher := range matchers {
+			if !matcher.Match(m.Fields) {
+				matched = false
+				break
+			}
+		}
+
+		if matched {
+			found = append(found, m)
+		}
+	}
+
+	return found
+}
+
+// TestingT is the part of testing.TB used by assertions
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// AssertLogged fails the test unless a message matching Find arguments was recorded
+func (l *RecordingLogger) AssertLogged(t TestingT, level Level, kind, substring string, matchers ...FieldMatcher) bool {
+	t.Helper()
+
+	if len(l.Find(level, kind, substring, matchers...)) > 0 {
+		return true
+	}
+
+	t.Errorf("expected %s %s message containing %q%s, recorded:\n%s", level, kind, substring, describeMatchers(matchers), l.dump())
+	return false
+}
+
+// AssertNotLogged fails the test if a message matching Find arguments was recorded
+func (l *RecordingLogger) AssertNotLogged(t TestingT, level Level, kind, substring string, matchers ...FieldMatcher) bool {
+	t.Helper()
+
+	found := l.Find(level, kind, substring, matchers...)
+	if len(found) == 0 {
+		return true
+	}
+
+	t.Errorf("unexpected %s %s message containing %q%s: %s", level, kind, substring, describeMatchers(matchers), found[0])
+	return false
+}
+
+// NoErrorsLogged fails the test if a message of error or more severe level was recorded
+func (l *RecordingLogger) NoErrorsLogged(t TestingT) bool {
+	t.Helper()
+
+	for _, m := range l.Messages() {
+		if m.Level <= LevelError {
+			t.Errorf("unexpected error logged: %s", m)
+			return false
+		}
+	}
+
+	return true
+}
+
+func describeMatchers(matchers []FieldMatcher) string {
+	if len(matchers) == 0 {
+		return ""
+	}
+
+	descriptions := make([]string, len(matchers))
+	for i, m := range matchers {
+		descriptions[i] = m.Description
+	}
+
+	return " with " + strings.Join(descriptions, ", ")
+}
+
+func (l *RecordingLogger) dump() string {
+	var sb strings.Builder
+	for _, m := range l.Messages() {
+		sb.WriteString("\t" + m.String() + "\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,171 @@
+package gobase
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+const redactedValue = "***"
+
+// Sensitive wraps a value that must never be logged, it always renders as "***"
+type Sensitive struct {
+	Value any
+}
+
+func (Sensitive) String() string {
+	return redactedValue
+}
+
+func (Sensitive) GoString() string {
+	return redactedValue
+}
+
+func (s Sensitive) Format(f fmt.State, verb rune) {
+	f.Write([]byte(redactedValue))
+}
+
+func (Sensitive) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + redactedValue + `"`), nil
+}
+
+func (Sensitive) MarshalText() ([]byte, error) {
+	return []byte(redactedValue), nil
+}
+
+// Redactor scrubs sensitive data from message text and fields before they are sent by a logger
+type Redactor struct {
+	// DenyFields are case-insensitive substrings of field names, values of matching fields are replaced
+	DenyFields []string
+	// ValuePatterns are replaced in message text and string values of all fields
+	ValuePatterns []*regexp.Regexp
+	// Hash replaces values with "sha256:" + hash prefix (correlation is still possible) instead of "***"
+	Hash bool
+	// HashSalt is prepended to values before hashing
+	HashSalt string
+}
+
+// DefaultRedactor is used by loggers unless configured otherwise.
+// Modify it before logging starts (it is not guarded for concurrent changes).
+var DefaultRedactor = &Redactor{
+	DenyFields: []string{
+		"password", "passwd", "secret", "token", "api_key", "apikey", "api_hash",
+		"session", "phone", "authorization", "cookie", "private_key",
+	},
+	ValuePatterns: []*regexp.Regexp{
+		regexp.MustCompile(`\b\d{8,10}:[A-Za-z0-9_-]{35}\b`),                      // telegram bot token
+		regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`), // JWT
+		regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/=-]+`),                  // Authorization header value
+		regexp.MustCompile(`\+\d{10,15}\b`),                                       // phone number (E.164)
+	},
+}
+
+// IsDenied tells if field with this name must not be logged
+func (r *Redactor) IsDenied(field string) bool {
+	field = strings.ToLower(field)
+	for _, deny := range r.DenyFields {
+		if strings.Contains(field, deny) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactString replaces ValuePatterns matches in s
+func (r *Redactor) RedactString(s string) string {
+	for _, re := range r.ValuePatterns {
+		s = re.ReplaceAllStringFunc(s, r.replacement)
+	}
+	return s
+}
+
+// RedactFields returns copy of fields with denied fields, Sensitive values and ValuePatterns matches replaced.
+// Nested maps and slices are copied too, structs and other typed values are replaced with redacted JSON form
+// (objects, arrays, strings), fields is not modified.
+func (r *Redactor) RedactFields(fields map[string]any) map[string]any {
+	redacted := make(map[string]any, len(fields))
+
+	for k, v := range fields {
+		if r.IsDenied(k) {
+			redacted[k] = r.replacement(fmt.Sprint(unwrapSensitive(v)))
+		} else {
+			redacted[k] = r.redactValue(v)
+		}
+	}
+
+	return redacted
+}
+
+func (r *Redactor) redactValue(v any) any {
+	switch v := v.(type) {
+	case Sensitive:
+		return redactedValue
+	case *Sensitive:
+		return redactedValue
+	case string:
+		return r.RedactString(v)
+	case error:
+		return r.RedactString(v.Error())
+	case map[string]any:
+		return r.RedactFields(v)
+	case []any:
+		values := make([]any, len(v))
+		for i := range v {
+			values[i] = r.redactValue(v[i])
+		}
+		return values
+	case nil, json.Number:
+		return v
+	}
+
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		// Numbers, including Level and time.Duration, are kept as is
+		return v
+	default:
+		return r.redactJSON(v)
+	}
+}
+
+// redactJSON redacts structs, typed maps and slices in their JSON form, as they are sent by writers
+func (r *Redactor) redactJSON(v any) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return r.RedactString(fmt.Sprint(v))
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var decoded any
+	if err := decoder.Decode(&decoded); err != nil {
+		return r.RedactString(fmt.Sprint(v))
+	}
+
+	return r.redactValue(decoded)
+}
+
+func (r *Redactor) replacement(s string) string {
+	if !r.Hash {
+		return redactedValue
+	}
+
+	sum := sha256.Sum256([]byte(r.HashSalt + s))
+	return "sha256:" + hex.EncodeToString(sum[:6])
+}
+
+func unwrapSensitive(v any) any {
+	switch s := v.(type) {
+	case Sensitive:
+		return s.Value
+	case *Sensitive:
+		return s.Value
+	}
+	return v
+}
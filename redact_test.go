@@ -0,0 +1,119 @@
+package gobase
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-faster/errors"
+	"gopkg.in/Graylog2/go-gelf.v2/gelf"
+)
+
+func TestGetenvMapKeepsValues(t *testing.T) {
+	t.Setenv("GOBASE_TEST_BOT_TOKEN", "123:secret")
+
+	env := GetenvMap("GOBASE_TEST_BOT_TOKEN")
+	if v, ok := env["GOBASE_TEST_BOT_TOKEN"].(string); !ok || v != "123:secret" {
+		t.Fatalf("GetenvMap returned %#v", env["GOBASE_TEST_BOT_TOKEN"])
+	}
+
+	logger := NewRecordingLogger()
+	logger.Info("startup", "environment", env)
+
+	logger.AssertLogged(t, LevelInfo, "startup", "environment", FieldEquals("GOBASE_TEST_BOT_TOKEN", redactedValue))
+}
+
+func TestRedactFieldsDoesNotModifyInput(t *testing.T) {
+	fields := map[string]any{
+		"password": "hunter2",
+		"nested":   map[string]any{"token": "abc"},
+		"text":     "call +12345678901",
+		"wrapped":  Sensitive{"value"},
+	}
+
+	redacted := DefaultRedactor.RedactFields(fields)
+
+	if fields["password"] != "hunter2" || fields["nested"].(map[string]any)["token"] != "abc" {
+		t.Errorf("input modified: %v", fields)
+	}
+	if redacted["password"] != redactedValue || redacted["nested"].(map[string]any)["token"] != redactedValue ||
+		redacted["text"] != "call "+redactedValue || redacted["wrapped"] != redactedValue {
+		t.Errorf("unexpected redacted fields %v", redacted)
+	}
+}
+
+func TestDummyLoggerDoesNotModifyExtras(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	var out bytes.Buffer
+	log.SetOutput(&out)
+
+	extras := map[string]any{"password": "hunter2"}
+	DummyLogger{}.Message(LevelInfo, "test", "message", extras)
+
+	if extras["password"] != "hunter2" {
+		t.Errorf("extras modified: %v", extras)
+	}
+	if strings.Contains(out.String(), "hunter2") {
+		t.Errorf("secret printed: %s", out.String())
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) WriteMessage(*gelf.Message) error {
+	return errors.New("sink is down")
+}
+
+func (failingWriter) Close() error {
+	return nil
+}
+
+func TestFailedMessageDumpIsRedacted(t *testing.T) {
+	defer stderrLog.SetOutput(stderrLog.Writer())
+	var out bytes.Buffer
+	stderrLog.SetOutput(&out)
+
+	logger := newGelfLogger(failingWriter{}, "test", "test")
+	if logger.Message(LevelInfo, "test", "message", map[string]any{"password": "hunter2"}) {
+		t.Fatal("Message succeeded with failing sink")
+	}
+
+	if !strings.Contains(out.String(), "log not sent") || strings.Contains(out.String(), "hunter2") {
+		t.Errorf("unexpected stderr output: %s", out.String())
+	}
+}
+
+func TestRedactTypedValues(t *testing.T) {
+	type botConfig struct {
+		Name     string
+		BotToken string
+		Note     string `json:"note"`
+	}
+
+	redacted := DefaultRedactor.RedactFields(map[string]any{
+		"config":  botConfig{Name: "bot", BotToken: "abc", Note: "call +12345678901"},
+		"headers": map[string]string{"authorization": "Bearer abc", "accept": "*/*"},
+		"args":    []string{"-token", "Bearer abc"},
+		"count":   42,
+		"timeout": time.Second,
+	})
+
+	config := redacted["config"].(map[string]any)
+	if config["Name"] != "bot" || config["BotToken"] != redactedValue || config["note"] != "call "+redactedValue {
+		t.Errorf("struct not redacted: %v", config)
+	}
+
+	headers := redacted["headers"].(map[string]any)
+	if headers["authorization"] != redactedValue || headers["accept"] != "*/*" {
+		t.Errorf("map[string]string not redacted: %v", headers)
+	}
+
+	if args := redacted["args"].([]any); args[1] != redactedValue {
+		t.Errorf("[]string not redacted: %v", args)
+	}
+	if redacted["count"] != 42 || redacted["timeout"] != time.Second {
+		t.Errorf("number changed: %#v", redacted["count"])
+	}
+}
@@ -0,0 +1,158 @@
+package gobase
+
+import (
+	"regexp"
+	"sync"
+	"time"
+)
+
+// SamplingPolicy limits messages with the same (level, kind, message template) in every interval:
+// First messages are sent, then every Thereafter-th message is sent, others are dropped.
+type SamplingPolicy struct {
+	First      int
+	Thereafter int // 0 drops all messages after First
+}
+
+// Sampler drops repeated messages (incidents flooding Graylog) and reports the number of dropped ones
+// with a "suppressed N similar messages" summary at the end of the interval.
+// Levels without policy are never dropped.
+type Sampler struct {
+	Interval time.Duration
+	Policies map[Level]SamplingPolicy
+
+	// MaxKeys limits memory used for counters, counters of expired intervals are dropped when reached.
+	// If there are still too many, new messages of a level share one counter.
+	MaxKeys int
+
+	mu        sync.Mutex
+	counters  map[samplerKey]*samplerCounter
+	onSummary func(level Level, kind, template string, suppressed int)
+}
+
+const samplerOverflowTemplate = "(other messages)"
+
+// Longest template kept by sampler, longer messages are sampled by their beginning
+const samplerTemplateLimit = 256
+
+// templateValues are parts of formatted message text that differ between messages of the same template:
+// quoted strings, numbers, hex and UUID identifiers
+var templateValues = regexp.MustCompile(`"[^"]*"|'[^']*'|\b[0-9a-fA-F]+(-[0-9a-fA-F]+)+\b|\b[0-9a-fA-F]*[0-9][0-9a-fA-F]*\b|\d+`)
+
+// messageTemplate replaces values in formatted message text with "?", so that messages made with
+// fmt.Sprintf of the same format are sampled together
+func messageTemplate(message string) string {
+	template := templateValues.ReplaceAllString(message, "?")
+	if len(template) > samplerTemplateLimit {
+		template = template[:samplerTemplateLimit]
+	}
+	return template
+}
+
+type samplerKey struct {
+	level    Level
+	kind     string
+	template string
+}
+
+type samplerCounter struct {
+	windowEnd  time.Time
+	n          int
+	suppressed int
+	timer      *time.Timer
+}
+
+// NewSampler makes sampler applying the policy to levels from minLevel to LevelDebug,
+// more severe messages (e.g. critical, when minLevel is error) are never dropped.
+// minLevel more severe than LevelError is treated as LevelError.
+func NewSampler(interval time.Duration, minLevel Level, policy SamplingPolicy) *Sampler {
+	minLevel = max(minLevel, LevelError)
+
+	policies := map[Level]SamplingPolicy{}
+	for level := minLevel; level <= LevelDebug; level++ {
+		policies[level] = policy
+	}
+
+	return &Sampler{
+		Interval: interval,
+		Policies: policies,
+		MaxKeys:  10000,
+	}
+}
+
+// Allow counts the message and tells if it must be sent. template is printf format or message text
+// with values replaced (see messageTemplate). Critical and more severe messages are always sent.
+func (s *Sampler) Allow(level Level, kind, template string) bool {
+	if level <= LevelCritical {
+		return true
+	}
+
+	policy, ok := s.Policies[level]
+	if !ok {
+		return true
+	}
+
+	key := samplerKey{level, kind, template}
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.counters == nil {
+		s.counters = map[samplerKey]*samplerCounter{}
+	}
+
+	c, ok := s.counters[key]
+	if !ok && s.MaxKeys > 0 && len(s.counters) >= s.MaxKeys {
+		s.dropExpired(now)
+		if len(s.counters) >= s.MaxKeys {
+			// Too many distinct messages, the rest share a counter of their level
+			key = samplerKey{level: level, template: samplerOverflowTemplate}
+			c, ok = s.counters[key]
+		}
+	}
+	if !ok || now.After(c.windowEnd) {
+		c = &samplerCounter{windowEnd: now.Add(s.Interval)}
+		s.counters[key] = c
+	}
+
+	c.n++
+
+	if c.n <= policy.First {
+		return true
+	}
+
+	if policy.Thereafter > 0 && (c.n-policy.First)%policy.Thereafter == 0 {
+		return true
+	}
+
+	c.suppressed++
+
+	if c.timer == nil && s.onSummary != nil {
+		c.timer = time.AfterFunc(time.Until(c.windowEnd), func() {
+			s.summarize(key, c)
+		})
+	}
+
+	return false
+}
+
+func (s *Sampler) summarize(key samplerKey, c *samplerCounter) {
+	s.mu.Lock()
+	suppressed := c.suppressed
+	c.suppressed = 0
+	onSummary := s.onSummary
+	s.mu.Unlock()
+
+	if suppressed > 0 && onSummary != nil {
+		onSummary(key.level, key.kind, key.template, suppressed)
+	}
+}
+
+// dropExpired removes counters of finished intervals, those with pending summary are kept until reported
+func (s *Sampler) dropExpired(now time.Time) {
+	for k, c := range s.counters {
+		if now.After(c.windowEnd) && c.suppressed == 0 {
+			delete(s.counters, k)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package gobase
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMessageTemplate(t *testing.T) {
+	tests := map[string]string{
+		"user 42 not found":                 "user ? not found",
+		`chat "general" closed`:             "chat ? closed",
+		"request 9b2f61c0-7d3e-4a39 failed": "request ? failed",
+		"took 1.5s":                         "took ?.?s",
+		"connection refused":                "connection refused",
+	}
+
+	for message, want := range tests {
+		if got := messageTemplate(message); got != want {
+			t.Errorf("messageTemplate(%q) = %q, want %q", message, got, want)
+		}
+	}
+}
+
+func TestSamplerFormattedMessages(t *testing.T) {
+	logger := NewRecordingLogger()
+	logger.SetSampler(NewSampler(time.Hour, LevelError, SamplingPolicy{First: 2}))
+
+	for i := 0; i < 10; i++ {
+		logger.Message(LevelError, "db", fmt.Sprintf("query %d failed", i))
+		logger.Errorf("db", "user %d not found", i)
+	}
+
+	if n := len(logger.Messages()); n != 4 {
+		t.Errorf("sent %d messages, want 4: %v", n, logger.Messages())
+	}
+	if dropped := logger.Stats().Dropped; dropped != 16 {
+		t.Errorf("dropped %d messages, want 16", dropped)
+	}
+}
+
+func TestSamplerNeverDropsCritical(t *testing.T) {
+	s := NewSampler(time.Hour, LevelEmergency, SamplingPolicy{First: 1})
+	s.Policies[LevelCritical] = SamplingPolicy{}
+
+	for i := 0; i < 10; i++ {
+		if !s.Allow(LevelCritical, "panic", "boom") || !s.Allow(LevelEmergency, "panic", "boom") {
+			t.Fatal("critical message dropped")
+		}
+	}
+
+	if !s.Allow(LevelError, "db", "x") || s.Allow(LevelError, "db", "x") {
+		t.Error("error messages are not sampled")
+	}
+}
+
+func TestSamplerMaxKeys(t *testing.T) {
+	s := NewSampler(time.Hour, LevelError, SamplingPolicy{First: 1})
+	s.MaxKeys = 10
+
+	for i := 0; i < 100; i++ {
+		s.Allow(LevelError, "db", fmt.Sprintf("message %d", i))
+	}
+
+	if n := len(s.counters); n > s.MaxKeys+1 {
+		t.Errorf("sampler keeps %d counters, MaxKeys is %d", n, s.MaxKeys)
+	}
+	if s.Allow(LevelError, "db", "message 99") {
+		t.Error("message beyond MaxKeys is not sampled")
+	}
+}
@@ -0,0 +1,199 @@
+package gobase
+
+import (
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-faster/errors"
+	"google.golang.org/grpc"
+)
+
+// ExitFunc terminates the process, replace it (and Shutdown) in tests of crash paths (LogPanicExit, Exit)
+var ExitFunc = os.Exit
+
+// Default time given to shutdown hooks by Exit
+const DefaultShutdownDeadline = 5 * time.Second
+
+// Time loggers added with AddLogger have to close after other hooks, on top of the shutdown deadline
+const ShutdownLoggersDeadline = time.Second
+
+// Time gRPC servers added with AddGRPCServer have to finish calls before they are stopped forcibly.
+// A call may never finish, e.g. its handler is the one exiting the process in LogPanicExit.
+const GRPCStopGracePeriod = time.Second
+
+var (
+	shutdownDeadline atomic.Int64 // time.Duration
+	panicExitCode    atomic.Int32
+)
+
+func init() {
+	shutdownDeadline.Store(int64(DefaultShutdownDeadline))
+	panicExitCode.Store(1)
+}
+
+// SetPanicExit sets exit code of LogPanicExit and time shutdown hooks have before Exit terminates the process
+func SetPanicExit(code int, deadline time.Duration) {
+	panicExitCode.Store(int32(code))
+	shutdownDeadline.Store(int64(deadline))
+}
+
+type shutdownHook struct {
+	name string
+	hook func(ctx context.Context) error
+}
+
+// ShutdownHooks run cleanup (flush loggers, stop queues, close gRPC servers) before the process exits.
+// Hooks run once, in reverse order of adding, like deferred calls. Loggers are flushed before and closed after them.
+type ShutdownHooks struct {
+	mu      sync.Mutex
+	hooks   []shutdownHook
+	loggers []shutdownHook
+	flushes []shutdownHook
+	ran     bool
+}
+
+// Shutdown hooks run by Exit and LogPanicExit
+var Shutdown = &ShutdownHooks{}
+
+// Add registers hook, name is used in error messages
+func (s *ShutdownHooks) Add(name string, hook func(ctx context.Context) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.hooks = append(s.hooks, shutdownHook{name: name, hook: hook})
+}
+
+// AddLogger flushes logger (if its sink is buffered) before other hooks run, so that messages logged before shutdown
+// are sent even if hooks use up the deadline, and closes it after them, within ShutdownLoggersDeadline
+func (s *ShutdownHooks) AddLogger(name string, l Logger) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if f, ok := l.(interface{ Flush() }); ok {
+		s.flushes = append(s.flushes, shutdownHook{name: name, hook: func(context.Context) error {
+			f.Flush()
+			return nil
+		}})
+	}
+
+	s.loggers = append(s.loggers, shutdownHook{name: name, hook: func(context.Context) error {
+		return l.Close()
+	}})
+}
+
+// AddJobQueue stops queue, if it is running
+func (s *ShutdownHooks) AddJobQueue(q *JobQueue) {
+	s.Add("queue "+q.name, func(context.Context) error {
+		if q.IsReady() {
+			q.Stop()
+		}
+		return nil
+	})
+}
+
+// AddGRPCServer stops server gracefully, or forcibly after GRPCStopGracePeriod or when deadline comes
+func (s *ShutdownHooks) AddGRPCServer(name string, server *grpc.Server) {
+	s.Add(name, func(ctx context.Context) error {
+		stopped := make(chan struct{})
+		go func() {
+			server.GracefulStop()
+			close(stopped)
+		}()
+
+		grace := time.NewTimer(GRPCStopGracePeriod)
+		defer grace.Stop()
+
+		select {
+		case <-stopped:
+			return nil
+		case <-grace.C:
+			server.Stop()
+			return nil
+		case <-ctx.Done():
+			server.Stop()
+			return ctx.Err()
+		}
+	})
+}
+
+// Run flushes loggers and runs hooks until ctx is done, then closes loggers within ShutdownLoggersDeadline.
+// Only the first call runs them (see Reset).
+// A hook not finished by the deadline is abandoned, remaining hooks still run with the done ctx.
+func (s *ShutdownHooks) Run(ctx context.Context) error {
+	s.mu.Lock()
+	if s.ran {
+		s.mu.Unlock()
+		return nil
+	}
+	s.ran = true
+	hooks, loggers, flushes := s.hooks, s.loggers, s.flushes
+	s.mu.Unlock()
+
+	errs := runShutdownHooks(ctx, flushes)
+	errs = append(errs, runShutdownHooks(ctx, hooks)...)
+
+	loggersCtx, cancel := context.WithTimeout(context.Background(), ShutdownLoggersDeadline)
+	defer cancel()
+	errs = append(errs, runShutdownHooks(loggersCtx, loggers)...)
+
+	return errors.Join(errs...)
+}
+
+// Reset lets Run run hooks again, for tests
+func (s *ShutdownHooks) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ran = false
+}
+
+// runShutdownHooks runs hooks in reverse order until ctx is done
+func runShutdownHooks(ctx context.Context, hooks []shutdownHook) []error {
+	var errs []error
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		h := hooks[i]
+
+		done := make(chan error, 1)
+		go func() {
+			var err error
+			defer func() { done <- err }()
+			defer LogPanicErr(&err, DefaultLogger(), "shutdown", h.name)
+
+			err = h.hook(ctx)
+		}()
+
+		var err error
+		select {
+		case err = <-done:
+		case <-ctx.Done():
+			// Hooks run after the deadline still get a chance to finish immediately
+			select {
+			case err = <-done:
+			default:
+				err = ctx.Err()
+			}
+		}
+
+		if err != nil {
+			errs = append(errs, errors.Wrap(err, h.name))
+		}
+	}
+
+	return errs
+}
+
+// Exit runs Shutdown hooks (bounded by deadline set with SetPanicExit) and terminates the process with ExitFunc
+func Exit(code int) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(shutdownDeadline.Load()))
+	defer cancel()
+
+	if err := Shutdown.Run(ctx); err != nil {
+		stderrLog.Println("ERROR shutdown:", err)
+	}
+
+	ExitFunc(code)
+}
@@ -0,0 +1,168 @@
+package gobase
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type shutdownEvents struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (e *shutdownEvents) add(event string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.events = append(e.events, event)
+}
+
+func (e *shutdownEvents) String() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	s := ""
+	for _, event := range e.events {
+		s += event + " "
+	}
+	return s
+}
+
+type shutdownTestLogger struct {
+	*RecordingLogger
+	events *shutdownEvents
+}
+
+func (l shutdownTestLogger) Flush() {
+	l.events.add("flush")
+}
+
+func (l shutdownTestLogger) Close() error {
+	l.events.add("close")
+	return nil
+}
+
+func TestShutdownHooksOrder(t *testing.T) {
+	events := &shutdownEvents{}
+	s := &ShutdownHooks{}
+
+	s.AddLogger("logger", shutdownTestLogger{NewRecordingLogger(), events})
+	s.Add("first", func(context.Context) error { events.add("first"); return nil })
+	s.Add("second", func(context.Context) error { events.add("second"); return nil })
+
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if events.String() != "flush second first close " {
+		t.Errorf("unexpected order: %s", events)
+	}
+
+	s.Run(context.Background())
+	if events.String() != "flush second first close " {
+		t.Errorf("hooks ran twice: %s", events)
+	}
+
+	s.Reset()
+	s.Run(context.Background())
+	if events.String() != "flush second first close flush second first close " {
+		t.Errorf("hooks did not run after Reset: %s", events)
+	}
+}
+
+func TestShutdownLoggerClosedAfterDeadline(t *testing.T) {
+	events := &shutdownEvents{}
+	s := &ShutdownHooks{}
+
+	s.AddLogger("logger", shutdownTestLogger{NewRecordingLogger(), events})
+	s.Add("slow", func(ctx context.Context) error { <-ctx.Done(); time.Sleep(10 * time.Millisecond); return nil })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.Run(ctx); err == nil {
+		t.Error("no error of hook exceeding deadline")
+	}
+
+	if events.String() != "flush close " {
+		t.Errorf("logger not flushed and closed: %s", events)
+	}
+}
+
+func TestShutdownGRPCServerWithBlockedCall(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := grpc.NewServer()
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(l)
+
+	conn, err := grpc.NewClient(l.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Watch stream does not end until the client cancels it
+	stream, err := healthpb.NewHealthClient(conn).Watch(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+
+	events := &shutdownEvents{}
+	s := &ShutdownHooks{}
+	s.AddLogger("logger", shutdownTestLogger{NewRecordingLogger(), events})
+	s.AddGRPCServer("grpc", server)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	started := time.Now()
+	if err := s.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if elapsed := time.Since(started); elapsed > GRPCStopGracePeriod+time.Second {
+		t.Errorf("server stopped in %s", elapsed)
+	}
+	if events.String() != "flush close " {
+		t.Errorf("logger not flushed and closed: %s", events)
+	}
+}
+
+func TestLogPanicExitContinuesPanicWhenExitReturns(t *testing.T) {
+	defer func(exit func(int)) { ExitFunc = exit }(ExitFunc)
+	defer Shutdown.Reset()
+
+	code := -1
+	ExitFunc = func(c int) { code = c }
+
+	logger := NewRecordingLogger()
+
+	defer func() {
+		if r := recover(); r != "exit" {
+			t.Errorf("recovered %v", r)
+		}
+		if code != 1 {
+			t.Errorf("exit code %d", code)
+		}
+		logger.AssertLogged(t, LevelCritical, "main", "panic: exit")
+	}()
+
+	func() {
+		defer LogPanicExit(logger, "main")
+		panic("exit")
+	}()
+
+	t.Error("panic did not continue")
+}
@@ -0,0 +1,237 @@
+package gobase
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-faster/errors"
+	"gopkg.in/Graylog2/go-gelf.v2/gelf"
+)
+
+// Syslog facility used when no other is configured ("user-level messages")
+const SyslogFacilityUser = 1
+
+// Structured data ID for message fields (32473 is the private enterprise number reserved for documentation)
+const SyslogStructuredDataID = "fields@32473"
+
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19, "local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// ParseSyslogFacility converts facility name ("daemon", "local0", ...) or number to syslog facility code
+func ParseSyslogFacility(s string) (int, error) {
+	if v, ok := syslogFacilities[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 0 || v > 23 {
+		return 0, errors.Errorf("Invalid syslog facility '%s'", s)
+	}
+
+	return v, nil
+}
+
+// SyslogWriter sends GELF messages to syslog daemon in RFC 5424 format:
+//
+//	<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [fields@32473 key="value" ...] kind: message
+//
+// Level is syslog severity, APP-NAME is GELF facility, MSGID is message kind, message fields are structured data.
+// Transports: "udp" and "unixgram" send a message per datagram,
+// "tcp" and "unix" (stream socket) use octet-counted framing (RFC 6587).
+type SyslogWriter struct {
+	mu       sync.Mutex
+	network  string
+	addr     string
+	conn     net.Conn
+	datagram bool // connected network sends message per datagram, otherwise octet-counted framing is used
+	facility int
+}
+
+// NewSyslogWriter connects to syslog daemon.
+// network is "udp", "tcp", "unix" or "unixgram" (addr is socket path, e.g. /dev/log);
+// for "unix" datagram socket is tried first, like local syslog daemons expect.
+func NewSyslogWriter(network, addr string, facility int) (*SyslogWriter, error) {
+	w := &SyslogWriter{
+		network:  network,
+		addr:     addr,
+		facility: facility,
+	}
+
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func (w *SyslogWriter) connect() error {
+	if w.network == "unix" {
+		if conn, err := net.Dial("unixgram", w.addr); err == nil {
+			w.conn, w.datagram = conn, true
+			return nil
+		}
+	}
+
+	conn, err := net.Dial(w.network, w.addr)
+	if err != nil {
+		return err
+	}
+
+	w.conn = conn
+	w.datagram = strings.HasPrefix(w.network, "udp") || w.network == "unixgram"
+	return nil
+}
+
+// FormatSyslogMessage formats message according to RFC 5424
+func FormatSyslogMessage(m *gelf.Message, facility int) string {
+	var sb strings.Builder
+
+	ts := time.Unix(0, int64(m.TimeUnix*float64(time.Second)))
+
+	fmt.Fprintf(&sb, "<%d>1 %s %s %s %d %s ",
+		facility*8+int(m.Level),
+		ts.Format(time.RFC3339Nano),
+		syslogHeaderValue(m.Host, 255),
+		syslogHeaderValue(m.Facility, 48),
+		os.Getpid(),
+		syslogHeaderValue(m.Short, 32),
+	)
+
+	sb.WriteString(syslogStructuredData(m.Extra))
+
+	sb.WriteByte(' ')
+	sb.WriteString(m.Short)
+	if m.Full != "" {
+		sb.WriteString(": ")
+		sb.WriteString(m.Full)
+	}
+
+	return sb.String()
+}
+
+func (w *SyslogWriter) WriteMessage(m *gelf.Message) error {
+	_, err := w.writeMessage(m)
+	return err
+}
+
+func (w *SyslogWriter) writeMessage(m *gelf.Message) (int, error) {
+	msg := FormatSyslogMessage(m, w.facility)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n, err := w.write(msg)
+	if err != nil {
+		// Reconnect once (syslog daemon restarted)
+		w.conn.Close()
+		if dialErr := w.connect(); dialErr != nil {
+			return n, err
+		}
+
+		if n > 0 {
+			// Part of the frame was sent, sending it again could duplicate the message
+			return n, err
+		}
+
+		return w.write(msg)
+	}
+
+	return n, nil
+}
+
+// write sends message, returns number of bytes written
+func (w *SyslogWriter) write(msg string) (int, error) {
+	if w.datagram {
+		return w.conn.Write([]byte(msg))
+	}
+
+	return w.conn.Write([]byte(strconv.Itoa(len(msg)) + " " + msg))
+}
+
+func (w *SyslogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.conn.Close()
+}
+
+// syslogHeaderValue returns printable ASCII value limited to maxLen, or NILVALUE for empty header fields
+func syslogHeaderValue(s string, maxLen int) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return '_'
+		}
+		return r
+	}, s)
+
+	if len(s) > maxLen {
+		s = s[:maxLen]
+	}
+
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
+
+// syslogStructuredData formats fields as single SD-ELEMENT, or NILVALUE if there are none
+func syslogStructuredData(fields map[string]any) string {
+	if len(fields) == 0 {
+		return "-"
+	}
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteString("[" + SyslogStructuredDataID)
+
+	for _, k := range keys {
+		sb.WriteString(" ")
+		sb.WriteString(syslogParamName(k))
+		sb.WriteString(`="`)
+		sb.WriteString(syslogParamValue(fmt.Sprint(fields[k])))
+		sb.WriteString(`"`)
+	}
+
+	sb.WriteString("]")
+
+	return sb.String()
+}
+
+// syslogParamName makes valid SD-NAME: 1 to 32 printable ASCII characters except '=', ' ', ']', '"'
+func syslogParamName(s string) string {
+	if s == "" {
+		return "_"
+	}
+
+	s = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, s)
+
+	if len(s) > 32 {
+		s = s[:32]
+	}
+
+	return s
+}
+
+// syslogParamValue escapes '"', '\' and ']' in PARAM-VALUE
+func syslogParamValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(s)
+}
@@ -0,0 +1,104 @@
+package gobase
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-faster/errors"
+	"gopkg.in/Graylog2/go-gelf.v2/gelf"
+)
+
+// partialWriteConn sends half of the data and fails, as a connection broken in the middle of a write
+type partialWriteConn struct {
+	net.Conn
+}
+
+func (c partialWriteConn) Write(p []byte) (int, error) {
+	n, _ := c.Conn.Write(p[:len(p)/2])
+	return n, errors.New("connection reset")
+}
+
+// readSyslogFrames reads complete octet-counted frames from accepted connections
+func readSyslogFrames(l net.Listener, frames chan<- string) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			defer conn.Close()
+
+			r := bufio.NewReader(conn)
+			for {
+				length, err := r.ReadString(' ')
+				if err != nil {
+					return
+				}
+				n, err := strconv.Atoi(strings.TrimSpace(length))
+				if err != nil {
+					frames <- "invalid frame length " + length
+					return
+				}
+
+				msg := make([]byte, n)
+				if _, err := io.ReadFull(r, msg); err != nil {
+					return
+				}
+				frames <- string(msg)
+			}
+		}()
+	}
+}
+
+func TestSyslogWriterNoDuplicateAfterPartialWrite(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	frames := make(chan string, 10)
+	go readSyslogFrames(l, frames)
+
+	w, err := NewSyslogWriter("tcp", l.Addr().String(), SyslogFacilityUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	w.conn = partialWriteConn{w.conn}
+	if err := w.WriteMessage(&gelf.Message{Short: "first", Level: gelf.LOG_INFO}); err == nil {
+		t.Error("partial write succeeded")
+	}
+	if err := w.WriteMessage(&gelf.Message{Short: "second", Level: gelf.LOG_INFO}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case frame := <-frames:
+		if !strings.HasSuffix(frame, " second") {
+			t.Errorf("unexpected frame %q", frame)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no frame received")
+	}
+
+	select {
+	case frame := <-frames:
+		t.Errorf("unexpected frame %q", frame)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSyslogStructuredDataEmptyName(t *testing.T) {
+	sd := syslogStructuredData(map[string]any{"": "x", "a b": 1})
+	if sd != `[`+SyslogStructuredDataID+` _="x" a_b="1"]` {
+		t.Errorf("unexpected structured data %s", sd)
+	}
+}